@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+)
+
+// adminIdentityContextKey marks requests that passed adminOnly, which submit with
+// the org's admin identity when one is loaded
+type adminIdentityContextKey struct{}
+
+// withAdminIdentity applies the org's admin identity environment settings:
+//
+//	FABRIC_ADMIN_CERT_<MSPID>  the admin's certificate and the keystore directory
+//	FABRIC_ADMIN_KEY_<MSPID>   holding its private key; both or neither
+//
+// The chaincode only lets admins run transactions such as InitLedger and
+// MigrateAssets, so admin routes can't use the User1 identity. By default the
+// test-network's Admin user is used.
+func withAdminIdentity(org orgConfig) (orgConfig, error) {
+	certName, certPath := orgEnv(org, "FABRIC_ADMIN_CERT")
+	keyName, keyPath := orgEnv(org, "FABRIC_ADMIN_KEY")
+	if (certPath == "") != (keyPath == "") {
+		return org, fmt.Errorf("%s and %s must be set together", certName, keyName)
+	}
+	if certPath != "" {
+		org.AdminCertPath = certPath
+		org.AdminKeyPath = keyPath
+		org.AdminFromEnv = true
+	}
+	return org, nil
+}
+
+// adminConfig returns the org's config with the admin identity in place of the
+// client identity, for building its admin gateway
+func adminConfig(org orgConfig) orgConfig {
+	org.CertPath = org.AdminCertPath
+	org.KeyPath = org.AdminKeyPath
+	return org
+}
+
+// connectAdminGateway builds the org's admin gateway on its connection. An org
+// without the default admin crypto material gets none, and its admin routes
+// submit with the client identity, which the chaincode rejects for admin-only
+// transactions. Configured admin material was checked by validateCryptoMaterial.
+func connectAdminGateway(org orgConnection) (*client.Gateway, error) {
+	if !org.config.AdminFromEnv && (checkFile(org.config.AdminCertPath) != "" || checkKeystore(org.config.AdminKeyPath) != "") {
+		log.Printf("No admin identity found for %s, admin routes will submit as its client identity", org.config.MSPID)
+		return nil, nil
+	}
+	gw, err := reloadGateway(orgConnection{config: adminConfig(org.config), conn: org.conn})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the admin identity of %s: %w", org.config.MSPID, err)
+	}
+	log.Printf("Loaded admin identity for %s from %s", org.config.MSPID, displayPath(org.config.AdminCertPath))
+	return gw, nil
+}
+
+// withAdminRequest marks the request as coming through adminOnly
+func withAdminRequest(r *http.Request) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), adminIdentityContextKey{}, true))
+}
+
+// isAdminRequest reports whether the request came through adminOnly
+func isAdminRequest(r *http.Request) bool {
+	admin, _ := r.Context().Value(adminIdentityContextKey{}).(bool)
+	return admin
+}
+
+// adminContractFor returns a contract on the org's admin gateway, or nil when
+// the org has no admin identity
+func (h *ApiHandler) adminContractFor(org string, channel string, chaincode string) *client.Contract {
+	h.gatewaysMu.RLock()
+	defer h.gatewaysMu.RUnlock()
+	gw := h.AdminGateways[org]
+	if gw == nil {
+		return nil
+	}
+	// Cached apart from the client identity's contract for the same org
+	return h.contracts.get(gw, "admin|"+org, channel, chaincode)
+}
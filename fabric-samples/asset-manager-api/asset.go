@@ -0,0 +1,226 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// Asset mirrors the asset record returned by the chaincode
+type Asset struct {
+	DEALERID    string `json:"DEALERID"`
+	MSISDN      string `json:"MSISDN,omitempty"` // Private data, only set on legacy records
+	MPIN        string `json:"MPIN,omitempty"`
+	BALANCE     string `json:"BALANCE"` // Decimal string with two places, e.g. "100.50"
+	STATUS      string `json:"STATUS"`
+	TRANSAMOUNT string `json:"TRANSAMOUNT"`
+	TRANSTYPE   string `json:"TRANSTYPE"`
+	REMARKS     string `json:"REMARKS"`
+	Currency    string `json:"Currency,omitempty"` // ISO 4217 code, empty on assets created before it existed
+
+	LastTransferTxID string `json:"LastTransferTxID,omitempty"`
+	CreatedAt        string `json:"CreatedAt,omitempty"`
+	LastModifiedBy   string `json:"LastModifiedBy,omitempty"`
+	SchemaVersion    int    `json:"SchemaVersion,omitempty"`
+
+	Metadata map[string]string `json:"Metadata,omitempty"`
+}
+
+// assetFields lists the JSON names of every Asset field
+var assetFields = []string{
+	"DEALERID", "MSISDN", "MPIN", "BALANCE", "STATUS", "TRANSAMOUNT", "TRANSTYPE", "REMARKS", "Currency",
+	"LastTransferTxID", "CreatedAt", "LastModifiedBy", "SchemaVersion", "Metadata",
+}
+
+// loadMaskedFields parses MASKED_FIELDS, the comma separated asset fields hidden
+// from restricted callers. It defaults to MPIN, MSISDN and REMARKS.
+func loadMaskedFields() map[string]bool {
+	value := os.Getenv("MASKED_FIELDS")
+	if value == "" {
+		value = "MPIN,MSISDN,REMARKS"
+	}
+
+	masked := make(map[string]bool)
+	for _, field := range strings.Split(value, ",") {
+		masked[strings.TrimSpace(field)] = true
+	}
+	return masked
+}
+
+// filterAsset returns the asset as a map holding only the visible fields
+func filterAsset(asset *Asset, visible map[string]bool) (map[string]any, error) {
+	assetJSON, err := json.Marshal(asset)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]any
+	if err := json.Unmarshal(assetJSON, &fields); err != nil {
+		return nil, err
+	}
+
+	for name := range fields {
+		if !visible[name] {
+			delete(fields, name)
+		}
+	}
+	return fields, nil
+}
+
+// visibleFields returns the asset fields the caller may see, or nil when
+// the caller may see the whole asset
+func (h *ApiHandler) visibleFields(role string) map[string]bool {
+	if role != roleRestricted {
+		return nil
+	}
+
+	visible := make(map[string]bool)
+	for _, field := range assetFields {
+		if !h.MaskedFields[field] {
+			visible[field] = true
+		}
+	}
+	return visible
+}
+
+// writeAsset writes a single asset returned by the chaincode, masking
+// sensitive fields for restricted callers
+func (h *ApiHandler) writeAsset(w http.ResponseWriter, r *http.Request, result []byte) {
+	asset, err := h.maskAsset(r, result)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, codeInternalError, fmt.Sprintf("Failed to mask asset: %s", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(asset)
+}
+
+// maskAsset hides the fields the caller may not see from a single asset returned by the chaincode
+func (h *ApiHandler) maskAsset(r *http.Request, result []byte) (json.RawMessage, error) {
+	visible := h.visibleFields(callerRole(r))
+	if visible == nil {
+		// The result from the chaincode is raw JSON, so it can be used as is
+		return result, nil
+	}
+
+	var asset Asset
+	if err := json.Unmarshal(result, &asset); err != nil {
+		return nil, err
+	}
+	filtered, err := filterAsset(&asset, visible)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(filtered)
+}
+
+// writeAssetList writes a list of assets returned by the chaincode in the order
+// asked for with ?sortBy, masking sensitive fields for restricted callers
+func (h *ApiHandler) writeAssetList(w http.ResponseWriter, r *http.Request, result []byte) {
+	// GetAllAssetsHandler has already rejected invalid sort options
+	if order, _ := parseAssetSort(r); order != nil {
+		sorted, err := sortAssetList(result, order)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, codeInternalError, fmt.Sprintf("Failed to sort assets: %s", err))
+			return
+		}
+		result = sorted
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	visible := h.visibleFields(callerRole(r))
+	if visible == nil {
+		// The result from the chaincode is raw JSON (likely an array), so write it directly
+		w.Write(result)
+		return
+	}
+
+	var assets []*Asset
+	if len(result) > 0 {
+		if err := json.Unmarshal(result, &assets); err != nil {
+			writeError(w, http.StatusInternalServerError, codeInternalError, fmt.Sprintf("Failed to parse assets: %s", err))
+			return
+		}
+	}
+	filtered := make([]map[string]any, 0, len(assets))
+	for _, asset := range assets {
+		fields, err := filterAsset(asset, visible)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, codeInternalError, fmt.Sprintf("Failed to mask asset: %s", err))
+			return
+		}
+		filtered = append(filtered, fields)
+	}
+	json.NewEncoder(w).Encode(filtered)
+}
+
+// writeHistory writes history records returned by the chaincode, masking
+// sensitive fields in each record for restricted callers
+func (h *ApiHandler) writeHistory(w http.ResponseWriter, r *http.Request, result []byte) {
+	w.Header().Set("Content-Type", "application/json")
+
+	visible := h.visibleFields(callerRole(r))
+	if visible == nil {
+		w.Write(result)
+		return
+	}
+
+	var records []map[string]any
+	if len(result) > 0 {
+		if err := json.Unmarshal(result, &records); err != nil {
+			writeError(w, http.StatusInternalServerError, codeInternalError, fmt.Sprintf("Failed to parse history: %s", err))
+			return
+		}
+	}
+	for _, record := range records {
+		maskHistoryRecord(record, visible)
+	}
+	if records == nil {
+		records = []map[string]any{}
+	}
+	json.NewEncoder(w).Encode(records)
+}
+
+// writeHistoryPage writes a page of history returned by the chaincode, masking
+// sensitive fields in each record for restricted callers
+func (h *ApiHandler) writeHistoryPage(w http.ResponseWriter, r *http.Request, result []byte) {
+	w.Header().Set("Content-Type", "application/json")
+
+	visible := h.visibleFields(callerRole(r))
+	if visible == nil {
+		w.Write(result)
+		return
+	}
+
+	var page map[string]any
+	if err := json.Unmarshal(result, &page); err != nil {
+		writeError(w, http.StatusInternalServerError, codeInternalError, fmt.Sprintf("Failed to parse history: %s", err))
+		return
+	}
+	if records, ok := page["records"].([]any); ok {
+		for _, record := range records {
+			if fields, ok := record.(map[string]any); ok {
+				maskHistoryRecord(fields, visible)
+			}
+		}
+	}
+	json.NewEncoder(w).Encode(page)
+}
+
+// maskHistoryRecord removes the fields that aren't visible from a history
+// record's asset and from its list of changes
+func maskHistoryRecord(record map[string]any, visible map[string]bool) {
+	for _, key := range []string{"record", "changes"} {
+		if fields, ok := record[key].(map[string]any); ok {
+			for name := range fields {
+				if !visible[name] {
+					delete(fields, name)
+				}
+			}
+		}
+	}
+}
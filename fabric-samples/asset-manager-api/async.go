@@ -0,0 +1,250 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+	"github.com/hyperledger/fabric-protos-go-apiv2/peer"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// asyncHeader makes submit handlers return 202 Accepted as soon as the transaction
+// has been sent to the orderer, instead of waiting for it to commit
+const asyncHeader = "X-Async"
+
+const asyncContextKey contextKey = "async"
+
+// Async transactions are remembered for asyncTransactionTTL so their status can be
+// looked up cheaply; a status request waits at most statusWaitTimeout for the commit
+const (
+	asyncTransactionTTL = time.Hour
+	statusWaitTimeout   = 2 * time.Second
+)
+
+// asyncSubmission collects the transaction ID of an async submit for asyncWriter
+type asyncSubmission struct {
+	txID string
+}
+
+// transactionStore remembers the commits of async submits by transaction ID
+type transactionStore struct {
+	mu      sync.Mutex
+	entries map[string]pendingTransaction
+}
+
+type pendingTransaction struct {
+	commit  *client.Commit
+	expires time.Time
+}
+
+func newTransactionStore() *transactionStore {
+	return &transactionStore{
+		entries: make(map[string]pendingTransaction),
+	}
+}
+
+// add remembers a submitted transaction, dropping expired ones
+func (s *transactionStore) add(commit *client.Commit) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for txID, entry := range s.entries {
+		if now.After(entry.expires) {
+			delete(s.entries, txID)
+		}
+	}
+	s.entries[commit.TransactionID()] = pendingTransaction{
+		commit:  commit,
+		expires: now.Add(asyncTransactionTTL),
+	}
+}
+
+// get returns the commit of a remembered transaction
+func (s *transactionStore) get(txID string) (*client.Commit, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[txID]
+	if !ok || time.Now().After(entry.expires) {
+		return nil, false
+	}
+	return entry.commit, true
+}
+
+// asyncMiddleware prepares X-Async requests: submitTransaction records the
+// transaction ID and asyncWriter turns the handler's success response into a 202
+func asyncMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		async, err := strconv.ParseBool(r.Header.Get(asyncHeader))
+		if err != nil || !async {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		submission := &asyncSubmission{}
+		aw := &asyncWriter{ResponseWriter: w, submission: submission}
+		next.ServeHTTP(aw, r.WithContext(context.WithValue(r.Context(), asyncContextKey, submission)))
+	})
+}
+
+// asyncWriter replaces the success response of an async submit with a 202 that
+// carries the transaction ID. Errors and responses of handlers that didn't
+// submit anything pass through unchanged.
+type asyncWriter struct {
+	http.ResponseWriter
+	submission  *asyncSubmission
+	wroteHeader bool
+	accepted    bool
+}
+
+func (aw *asyncWriter) WriteHeader(status int) {
+	if aw.wroteHeader {
+		return
+	}
+	aw.wroteHeader = true
+
+	if status < 200 || status >= 300 || aw.submission.txID == "" {
+		aw.ResponseWriter.WriteHeader(status)
+		return
+	}
+
+	aw.accepted = true
+	aw.ResponseWriter.Header().Set("Content-Type", "application/json")
+	aw.ResponseWriter.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(aw.ResponseWriter).Encode(map[string]string{
+		"message": "Transaction submitted, poll /api/transactions/" + aw.submission.txID + "/status for its commit status",
+		"txId":    aw.submission.txID,
+	})
+}
+
+func (aw *asyncWriter) Write(b []byte) (int, error) {
+	if !aw.wroteHeader {
+		aw.WriteHeader(http.StatusOK)
+	}
+	if aw.accepted {
+		// The handler's own success body is replaced by the 202 body
+		return len(b), nil
+	}
+	return aw.ResponseWriter.Write(b)
+}
+
+// Flush keeps streaming handlers working when a client sends X-Async anyway
+func (aw *asyncWriter) Flush() {
+	if flusher, ok := aw.ResponseWriter.(http.Flusher); ok && !aw.accepted {
+		flusher.Flush()
+	}
+}
+
+// submitAsync sends the transaction to the orderer without waiting for it to commit
+func (h *ApiHandler) submitAsync(contract *client.Contract, submission *asyncSubmission, name string, options ...client.ProposalOption) ([]byte, error) {
+	result, commit, err := contract.SubmitAsync(name, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	h.Transactions.add(commit)
+	submission.txID = commit.TransactionID()
+	log.Printf("<-- Transaction Submitted (async): %s, TxID: %s", name, submission.txID)
+	return result, nil
+}
+
+// transactionStatus is the commit status of a transaction. Only the transaction ID
+// and state are set while it is still pending.
+type transactionStatus struct {
+	TxID           string `json:"txId"`
+	State          string `json:"state"`
+	Successful     *bool  `json:"successful,omitempty"`
+	ValidationCode string `json:"validationCode,omitempty"`
+	BlockNumber    uint64 `json:"blockNumber,omitempty"`
+}
+
+// Transaction states reported by the status endpoint
+const (
+	statePending   = "PENDING"
+	stateCommitted = "COMMITTED"
+)
+
+// GetTransactionStatusHandler handles GET /api/transactions/{txId}/status
+// Async submits from this process are checked through their commit, any other
+// transaction is looked up on the ledger of the request's channel
+func (h *ApiHandler) GetTransactionStatusHandler(w http.ResponseWriter, r *http.Request) {
+	txID := mux.Vars(r)["txId"]
+
+	if commit, ok := h.Transactions.get(txID); ok {
+		ctx, cancel := context.WithTimeout(r.Context(), statusWaitTimeout)
+		commitStatus, err := commit.StatusWithContext(ctx)
+		cancel()
+
+		if errors.Is(err, context.DeadlineExceeded) || status.Code(err) == codes.DeadlineExceeded {
+			writeTransactionStatus(w, transactionStatus{TxID: txID, State: statePending})
+			return
+		}
+		if err != nil {
+			writeFabricError(w, "Failed to get commit status", err)
+			return
+		}
+
+		writeTransactionStatus(w, transactionStatus{
+			TxID:           txID,
+			State:          stateCommitted,
+			Successful:     &commitStatus.Successful,
+			ValidationCode: commitStatus.Code.String(),
+			BlockNumber:    commitStatus.BlockNumber,
+		})
+		return
+	}
+
+	org := h.requestOrg(r)
+	channel := requestChannel(r)
+	qscc := h.contractFor(org, channel, qsccName)
+
+	log.Printf("--> Evaluating Transaction: GetTransactionByID, TxID: %s", txID)
+	result, err := qscc.EvaluateTransaction("GetTransactionByID", channel, txID)
+	if err != nil {
+		if strings.Contains(strings.Join(fabricErrorMessages(err), "; "), "no such transaction ID") {
+			writeError(w, http.StatusNotFound, codeTransactionNotFound, "Transaction "+txID+" was not found")
+			return
+		}
+		writeFabricError(w, "Failed to evaluate transaction", err)
+		return
+	}
+	log.Printf("<-- Transaction Evaluated: GetTransactionByID, TxID: %s", txID)
+
+	var processed peer.ProcessedTransaction
+	if err := proto.Unmarshal(result, &processed); err != nil {
+		writeError(w, http.StatusInternalServerError, codeInternalError, "Failed to parse transaction: "+err.Error())
+		return
+	}
+	blockNumber, err := h.blockNumberForTx(r, txID)
+	if err != nil {
+		writeFabricError(w, "Failed to look up block", err)
+		return
+	}
+
+	code := peer.TxValidationCode(processed.GetValidationCode())
+	successful := code == peer.TxValidationCode_VALID
+	writeTransactionStatus(w, transactionStatus{
+		TxID:           txID,
+		State:          stateCommitted,
+		Successful:     &successful,
+		ValidationCode: code.String(),
+		BlockNumber:    blockNumber,
+	})
+}
+
+func writeTransactionStatus(w http.ResponseWriter, txStatus transactionStatus) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(txStatus)
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Audit entries are queued for a single writer goroutine. When the queue is full
+// entries are dropped rather than holding up the request.
+const (
+	auditQueueSize       = 1024
+	defaultAuditMaxBytes = 10 << 20
+	auditLogBackups      = 3 // Rotated files are kept as <file>.1 (newest) to <file>.3
+)
+
+// auditDealerArgs is how many leading arguments of a transaction are dealer IDs,
+// for transactions that are not submitted under an /{id} route
+var auditDealerArgs = map[string]int{
+	"BatchTransfer":           1, // The source, recipients are inside the JSON argument
+	"CreateAsset":             1,
+	"TransferBalance":         2,
+	"TransferBalanceWithRate": 2,
+}
+
+// auditEntry is one JSON line of the audit log, written for every submit
+type auditEntry struct {
+	Timestamp time.Time `json:"timestamp"`
+	Org       string    `json:"org"`
+	Role      string    `json:"role"`
+	Operation string    `json:"operation"`
+	DealerIDs []string  `json:"dealerIds,omitempty"`
+	TxID      string    `json:"txId,omitempty"`
+	Result    string    `json:"result"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// Audit results
+const (
+	auditCommitted = "COMMITTED"
+	auditSubmitted = "SUBMITTED" // Async submit, the commit isn't awaited
+	auditFailed    = "FAILED"
+)
+
+// auditLog appends submit records to a local file, independent of the ledger,
+// and rotates the file once it grows past maxBytes
+type auditLog struct {
+	path     string
+	maxBytes int64
+	file     *os.File
+	size     int64
+	entries  chan auditEntry
+	done     chan struct{} // Closed once run has written every queued entry
+
+	mu     sync.RWMutex // Guards closed against record sending on a closed queue
+	closed bool
+}
+
+// newAuditLog opens AUDIT_LOG_FILE for appending, rotating it after
+// AUDIT_LOG_MAX_BYTES (default 10MB). It returns nil when AUDIT_LOG_FILE is unset.
+func newAuditLog() (*auditLog, error) {
+	path := os.Getenv("AUDIT_LOG_FILE")
+	if path == "" {
+		return nil, nil
+	}
+
+	maxBytes := int64(defaultAuditMaxBytes)
+	if value := os.Getenv("AUDIT_LOG_MAX_BYTES"); value != "" {
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || parsed <= 0 {
+			log.Printf("Ignoring invalid AUDIT_LOG_MAX_BYTES %q, using %d", value, maxBytes)
+		} else {
+			maxBytes = parsed
+		}
+	}
+
+	a := &auditLog{
+		path:     path,
+		maxBytes: maxBytes,
+		entries:  make(chan auditEntry, auditQueueSize),
+		done:     make(chan struct{}),
+	}
+	if err := a.open(); err != nil {
+		return nil, err
+	}
+
+	go a.run()
+	log.Printf("Audit log enabled at %s", path)
+	return a, nil
+}
+
+func (a *auditLog) open() error {
+	file, err := os.OpenFile(a.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to stat audit log: %w", err)
+	}
+	a.file, a.size = file, info.Size()
+	return nil
+}
+
+// audit records a submit of operation made for the request. The caller is
+// identified by the org whose identity signed it and the role of its API key.
+func (h *ApiHandler) audit(r *http.Request, operation string, args []string, txID string, result string, err error) {
+	if h.Audit == nil {
+		return
+	}
+
+	entry := auditEntry{
+		Timestamp: time.Now().UTC(),
+		Org:       h.requestOrg(r),
+		Role:      callerRole(r),
+		Operation: operation,
+		DealerIDs: auditDealerIDs(r, operation, args),
+		TxID:      txID,
+		Result:    result,
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	h.Audit.record(entry)
+}
+
+// record queues an entry without waiting for it to be written
+func (a *auditLog) record(entry auditEntry) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.closed {
+		log.Printf("Audit log is closed, dropped entry for %s, TxID: %s", entry.Operation, entry.TxID)
+		return
+	}
+
+	select {
+	case a.entries <- entry:
+	default:
+		log.Printf("Audit log queue is full, dropped entry for %s, TxID: %s", entry.Operation, entry.TxID)
+	}
+}
+
+// auditDealerIDs returns the dealers a submit touches
+func auditDealerIDs(r *http.Request, operation string, args []string) []string {
+	if id := mux.Vars(r)["id"]; id != "" {
+		return []string{normalizeDealerID(id)}
+	}
+	n := auditDealerArgs[operation]
+	if n > len(args) {
+		n = len(args)
+	}
+	return args[:n]
+}
+
+// run writes queued entries until close
+func (a *auditLog) run() {
+	defer close(a.done)
+	for entry := range a.entries {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			log.Printf("Failed to encode audit entry: %v", err)
+			continue
+		}
+		line = append(line, '\n')
+
+		if a.file == nil || (a.size > 0 && a.size+int64(len(line)) > a.maxBytes) {
+			if err := a.rotate(); err != nil {
+				log.Printf("Failed to rotate audit log, dropped entry for %s: %v", entry.Operation, err)
+				continue
+			}
+		}
+		n, err := a.file.Write(line)
+		a.size += int64(n)
+		if err != nil {
+			log.Printf("Failed to write audit entry for %s: %v", entry.Operation, err)
+		}
+	}
+}
+
+// rotate shifts <file>.1 .. <file>.N-1 up by one, moves the current file to
+// <file>.1 and starts a new one. The oldest backup is overwritten.
+func (a *auditLog) rotate() error {
+	if a.file != nil {
+		a.file.Close()
+		a.file = nil
+
+		for i := auditLogBackups - 1; i >= 1; i-- {
+			os.Rename(fmt.Sprintf("%s.%d", a.path, i), fmt.Sprintf("%s.%d", a.path, i+1))
+		}
+		if err := os.Rename(a.path, a.path+".1"); err != nil {
+			return err
+		}
+	}
+	return a.open()
+}
+
+// close stops accepting entries, waits for the queued ones to be written and
+// then syncs and closes the file
+func (a *auditLog) close() error {
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		return nil
+	}
+	a.closed = true
+	close(a.entries)
+	a.mu.Unlock()
+
+	<-a.done
+	if a.file == nil {
+		return nil
+	}
+	if err := a.file.Sync(); err != nil {
+		a.file.Close()
+		return fmt.Errorf("failed to sync audit log: %w", err)
+	}
+	return a.file.Close()
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// apiKeyHeader carries the caller's API key
+const apiKeyHeader = "X-API-Key"
+
+// Roles a caller can hold
+const (
+	roleRestricted = "restricted" // sensitive asset fields are masked
+	roleFull       = "full"       // sees complete assets
+	roleAdmin      = "admin"      // full access plus admin operations
+)
+
+type contextKey string
+
+const roleContextKey contextKey = "role"
+
+// loadAPIKeys parses API_KEYS, a comma separated list of key=role entries.
+// When API_KEYS is empty authentication is disabled and every caller gets the full role.
+func loadAPIKeys() (map[string]string, error) {
+	keys := make(map[string]string)
+
+	value := os.Getenv("API_KEYS")
+	if value == "" {
+		return keys, nil
+	}
+
+	for _, entry := range strings.Split(value, ",") {
+		key, role, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid API_KEYS entry %q, expected key=role", entry)
+		}
+		switch role {
+		case roleRestricted, roleFull, roleAdmin:
+		default:
+			return nil, fmt.Errorf("invalid role %q in API_KEYS", role)
+		}
+		keys[key] = role
+	}
+	return keys, nil
+}
+
+// authMiddleware resolves the caller's role from their API key
+func (h *ApiHandler) authMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		role := roleFull
+		// Webhooks prove who sent them with their signature instead of an API key
+		if len(h.APIKeys) > 0 && !probePaths[r.URL.Path] && r.URL.Path != metricsPath && !isWebhookPath(r.URL.Path) {
+			var ok bool
+			role, ok = h.APIKeys[r.Header.Get(apiKeyHeader)]
+			if !ok {
+				writeError(w, http.StatusUnauthorized, codeUnauthorized, "Missing or invalid "+apiKeyHeader)
+				return
+			}
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), roleContextKey, role)))
+	})
+}
+
+// callerRole returns the role resolved by authMiddleware
+func callerRole(r *http.Request) string {
+	if role, ok := r.Context().Value(roleContextKey).(string); ok {
+		return role
+	}
+	return roleRestricted
+}
+
+// adminOnly rejects callers that don't hold the admin role.
+// With authentication disabled nobody is an admin, so admin routes are closed by default.
+// Admin routes submit with the org's admin identity, see withAdminIdentity.
+func adminOnly(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if callerRole(r) != roleAdmin {
+			writeError(w, http.StatusForbidden, codeForbidden, "This operation requires the admin role")
+			return
+		}
+		next(w, withAdminRequest(r))
+	}
+}
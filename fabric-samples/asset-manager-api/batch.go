@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+)
+
+// maxBatchReadIDs is the most dealer IDs the chaincode's ReadAssets accepts at once
+const maxBatchReadIDs = 100
+
+// assetReadResult is one entry of the chaincode's ReadAssets result
+type assetReadResult struct {
+	Asset *Asset `json:"asset,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// BatchReadAssetsHandler handles POST /api/assets/batch-read
+// The body is a JSON array of dealer IDs, at most 100, and the response maps each
+// normalized ID to {"asset":{...}} or, when it doesn't exist, {"error":"..."}
+func (h *ApiHandler) BatchReadAssetsHandler(w http.ResponseWriter, r *http.Request) {
+	var ids []string
+	if err := decodeJSONBody(r, &ids); err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, err.Error())
+		return
+	}
+	if len(ids) == 0 {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "the body must be a non-empty JSON array of dealer IDs")
+		return
+	}
+	for i, id := range ids {
+		ids[i] = normalizeDealerID(id)
+	}
+	idsJSON, err := json.Marshal(ids)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, codeInternalError, err.Error())
+		return
+	}
+
+	log.Printf("--> Evaluating Transaction: ReadAssets, Count: %d", len(ids))
+	result, err := h.contract(r).EvaluateTransaction("ReadAssets", string(idsJSON))
+	if err != nil {
+		writeFabricError(w, "Failed to evaluate transaction", err)
+		return
+	}
+	log.Printf("<-- Transaction Evaluated: ReadAssets, Count: %d", len(ids))
+
+	w.Header().Set("Content-Type", "application/json")
+	visible := h.visibleFields(callerRole(r))
+	if visible == nil {
+		w.Write(result)
+		return
+	}
+
+	var results map[string]assetReadResult
+	if err := json.Unmarshal(result, &results); err != nil {
+		writeError(w, http.StatusInternalServerError, codeInternalError, fmt.Sprintf("Failed to parse assets: %s", err))
+		return
+	}
+	filtered := make(map[string]map[string]any, len(results))
+	for id, read := range results {
+		if read.Asset == nil {
+			filtered[id] = map[string]any{"error": read.Error}
+			continue
+		}
+		asset, err := filterAsset(read.Asset, visible)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, codeInternalError, fmt.Sprintf("Failed to mask asset: %s", err))
+			return
+		}
+		filtered[id] = map[string]any{"asset": asset}
+	}
+	json.NewEncoder(w).Encode(filtered)
+}
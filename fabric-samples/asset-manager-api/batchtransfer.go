@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+)
+
+// maxDisbursements is the most recipients the chaincode's BatchTransfer accepts at once
+const maxDisbursements = 100
+
+// batchTransferResult is the chaincode's BatchTransfer result
+type batchTransferResult struct {
+	TxID         string `json:"txId"`
+	FromDealerID string `json:"fromDealerId"`
+	Total        string `json:"total"`
+	FromBalance  string `json:"fromBalance,omitempty"`
+	Results      []struct {
+		ToDealerID string `json:"toDealerId"`
+		Amount     string `json:"amount"`
+		Balance    string `json:"balance,omitempty"`
+	} `json:"results"`
+}
+
+// BatchTransferHandler handles POST /api/transfer/batch
+// The body is {"FROMDEALERID":"...","DISBURSEMENTS":[{"TODEALERID":"...","AMOUNT":"..."}]}
+// with at most 100 recipients, all in the source's currency. Everything is paid in
+// one transaction: the source is debited the total and, if any recipient is
+// invalid or the source can't cover the total, nothing is paid. The response
+// lists each recipient's amount and new BALANCE.
+func (h *ApiHandler) BatchTransferHandler(w http.ResponseWriter, r *http.Request) {
+	var batch struct {
+		FROMDEALERID  string `json:"FROMDEALERID"`
+		DISBURSEMENTS []struct {
+			TODEALERID string `json:"TODEALERID"`
+			AMOUNT     string `json:"AMOUNT"`
+		} `json:"DISBURSEMENTS"`
+	}
+	if err := decodeJSONBody(r, &batch); err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, err.Error())
+		return
+	}
+
+	batch.FROMDEALERID = normalizeDealerID(batch.FROMDEALERID)
+	if !requireDealerID(w, batch.FROMDEALERID) {
+		return
+	}
+	if len(batch.DISBURSEMENTS) == 0 {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "DISBURSEMENTS must list at least one recipient")
+		return
+	}
+	if len(batch.DISBURSEMENTS) > maxDisbursements {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, fmt.Sprintf("DISBURSEMENTS may list at most %d recipients, got %d", maxDisbursements, len(batch.DISBURSEMENTS)))
+		return
+	}
+
+	type disbursement struct {
+		ToDealerID string `json:"toDealerId"`
+		Amount     string `json:"amount"`
+	}
+	disbursements := make([]disbursement, 0, len(batch.DISBURSEMENTS))
+	dealerIDs := []string{batch.FROMDEALERID}
+	var problems []fieldError
+	for i, entry := range batch.DISBURSEMENTS {
+		toDealerID := normalizeDealerID(entry.TODEALERID)
+		if toDealerID == "" {
+			problems = append(problems, fieldError{Field: fmt.Sprintf("DISBURSEMENTS[%d].TODEALERID", i), Message: "TODEALERID must not be empty"})
+		}
+		amount, err := parseAmount("AMOUNT", entry.AMOUNT)
+		if err != nil {
+			problems = append(problems, fieldError{Field: fmt.Sprintf("DISBURSEMENTS[%d].AMOUNT", i), Message: err.Error()})
+		}
+		disbursements = append(disbursements, disbursement{ToDealerID: toDealerID, Amount: amount})
+		dealerIDs = append(dealerIDs, toDealerID)
+	}
+	if len(problems) > 0 {
+		writeErrorBody(w, http.StatusBadRequest, errorBody{
+			Code:    codeInvalidRequest,
+			Message: "invalid disbursements",
+			Fields:  problems,
+		})
+		return
+	}
+	disbursementsJSON, err := json.Marshal(disbursements)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, codeInternalError, err.Error())
+		return
+	}
+
+	log.Printf("--> Submitting Transaction: BatchTransfer, From: %s, Recipients: %d", batch.FROMDEALERID, len(disbursements))
+	result, err := h.submitTransaction(r, "BatchTransfer", batch.FROMDEALERID, string(disbursementsJSON))
+	h.invalidateReads(r, dealerIDs...)
+	if err != nil {
+		writeFabricError(w, "Failed to submit transaction", err)
+		return
+	}
+	log.Printf("<-- Transaction Committed: BatchTransfer, From: %s, Recipients: %d", batch.FROMDEALERID, len(disbursements))
+
+	var transfer batchTransferResult
+	if err := json.Unmarshal(result, &transfer); err != nil {
+		writeError(w, http.StatusInternalServerError, codeInternalError, fmt.Sprintf("Failed to parse the transfer result: %s", err))
+		return
+	}
+	if visible := h.visibleFields(callerRole(r)); visible != nil && !visible["BALANCE"] {
+		transfer.FromBalance = ""
+		for i := range transfer.Results {
+			transfer.Results[i].Balance = ""
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(transfer)
+}
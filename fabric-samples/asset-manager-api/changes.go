@@ -0,0 +1,283 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+	"github.com/hyperledger/fabric-protos-go-apiv2/common"
+	"github.com/hyperledger/fabric-protos-go-apiv2/ledger/rwset"
+	"github.com/hyperledger/fabric-protos-go-apiv2/ledger/rwset/kvrwset"
+	"github.com/hyperledger/fabric-protos-go-apiv2/peer"
+	"google.golang.org/protobuf/proto"
+)
+
+// maxChangeBlocks bounds how many blocks one changes request replays. Longer
+// ranges are read in several requests by following lastBlock.
+const maxChangeBlocks = 1000
+
+// assetChange is an asset written after the requested block, with its current state
+type assetChange struct {
+	DealerID    string         `json:"dealerId"`
+	BlockNumber uint64         `json:"blockNumber"` // The last block in range that wrote the asset
+	Deleted     bool           `json:"deleted"`
+	Asset       map[string]any `json:"asset,omitempty"`
+}
+
+// assetChanges is the response of GET /api/assets/changes
+type assetChanges struct {
+	SinceBlock uint64        `json:"sinceBlock"`
+	LastBlock  uint64        `json:"lastBlock"` // Pass as sinceBlock to continue from here
+	HasMore    bool          `json:"hasMore"`
+	Changes    []assetChange `json:"changes"`
+}
+
+// GetAssetChangesHandler handles GET /api/assets/changes?sinceBlock=N
+// It replays the blocks after N, up to 1000 of them, and returns every dealer ID
+// the chaincode wrote in a valid transaction along with its current state. Deleted
+// assets have "deleted": true and no asset.
+func (h *ApiHandler) GetAssetChangesHandler(w http.ResponseWriter, r *http.Request) {
+	sinceBlock, err := strconv.ParseUint(r.URL.Query().Get("sinceBlock"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "sinceBlock must be a block number")
+		return
+	}
+
+	info, err := h.chainInfo(r)
+	if err != nil {
+		writeFabricError(w, "Failed to read chain info", err)
+		return
+	}
+	if info.GetHeight() == 0 {
+		writeError(w, http.StatusInternalServerError, codeInternalError, "the channel has no blocks")
+		return
+	}
+
+	changes := assetChanges{
+		SinceBlock: sinceBlock,
+		LastBlock:  info.GetHeight() - 1,
+		Changes:    []assetChange{},
+	}
+	if sinceBlock >= changes.LastBlock {
+		changes.LastBlock = sinceBlock
+		writeAssetChanges(w, changes)
+		return
+	}
+	if changes.LastBlock-sinceBlock > maxChangeBlocks {
+		changes.LastBlock = sinceBlock + maxChangeBlocks
+		changes.HasMore = true
+	}
+
+	written, err := h.replayAssetWrites(r, sinceBlock+1, changes.LastBlock)
+	if err != nil {
+		writeFabricError(w, "Failed to replay blocks", err)
+		return
+	}
+
+	ids := make([]string, 0, len(written))
+	for id := range written {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	current, err := h.readAssetsBatched(r, ids)
+	if err != nil {
+		writeFabricError(w, "Failed to evaluate transaction", err)
+		return
+	}
+	visible := h.visibleFields(callerRole(r))
+	if visible == nil {
+		visible = allFields()
+	}
+	for _, id := range ids {
+		change := assetChange{DealerID: id, BlockNumber: written[id]}
+		if read := current[id]; read.Asset == nil {
+			change.Deleted = true
+		} else {
+			change.Asset, err = filterAsset(read.Asset, visible)
+			if err != nil {
+				writeError(w, http.StatusInternalServerError, codeInternalError, fmt.Sprintf("Failed to mask asset: %s", err))
+				return
+			}
+		}
+		changes.Changes = append(changes.Changes, change)
+	}
+
+	writeAssetChanges(w, changes)
+}
+
+// writeAssetChanges writes the changes response
+func writeAssetChanges(w http.ResponseWriter, changes assetChanges) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(changes)
+}
+
+// allFields returns every asset field, for callers that may see the whole asset
+func allFields() map[string]bool {
+	visible := make(map[string]bool, len(assetFields))
+	for _, field := range assetFields {
+		visible[field] = true
+	}
+	return visible
+}
+
+// replayAssetWrites reads blocks first to last with block events and returns the
+// dealer IDs written by valid transactions of the request's chaincode, mapped to
+// the last block that wrote them
+func (h *ApiHandler) replayAssetWrites(r *http.Request, first uint64, last uint64) (map[string]uint64, error) {
+	channel := requestChannel(r)
+	network := h.gateway(h.requestOrg(r)).GetNetwork(channel)
+
+	// Stop the event stream once the last block has been read
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	log.Printf("--> Replaying blocks %d to %d, Channel: %s", first, last, channel)
+	blocks, err := network.BlockEvents(ctx, client.WithStartBlock(first))
+	if err != nil {
+		return nil, err
+	}
+
+	written := make(map[string]uint64)
+	for block := range blocks {
+		number := block.GetHeader().GetNumber()
+		keys, err := blockAssetWrites(block, h.Channels[channel])
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse block %d: %w", number, err)
+		}
+		for _, key := range keys {
+			written[key] = number
+		}
+		if number >= last {
+			log.Printf("<-- Replayed blocks %d to %d, %d assets changed", first, last, len(written))
+			return written, nil
+		}
+	}
+
+	// The stream only ends early when the request is cancelled or the peer fails
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("the block event stream ended before block %d", last)
+}
+
+// blockAssetWrites returns the world state keys that the block's valid transactions
+// wrote in the chaincode's namespace. Composite keys, such as indexes and settings,
+// are not assets and are skipped.
+func blockAssetWrites(block *common.Block, chaincode string) ([]string, error) {
+	var validation []byte
+	if metadata := block.GetMetadata().GetMetadata(); len(metadata) > int(common.BlockMetadataIndex_TRANSACTIONS_FILTER) {
+		validation = metadata[common.BlockMetadataIndex_TRANSACTIONS_FILTER]
+	}
+
+	var keys []string
+	for i, envelopeBytes := range block.GetData().GetData() {
+		if i >= len(validation) || peer.TxValidationCode(validation[i]) != peer.TxValidationCode_VALID {
+			continue
+		}
+
+		envelope := &common.Envelope{}
+		if err := proto.Unmarshal(envelopeBytes, envelope); err != nil {
+			return nil, err
+		}
+		payload := &common.Payload{}
+		if err := proto.Unmarshal(envelope.GetPayload(), payload); err != nil {
+			return nil, err
+		}
+		header := &common.ChannelHeader{}
+		if err := proto.Unmarshal(payload.GetHeader().GetChannelHeader(), header); err != nil {
+			return nil, err
+		}
+		if common.HeaderType(header.GetType()) != common.HeaderType_ENDORSER_TRANSACTION {
+			continue
+		}
+
+		tx := &peer.Transaction{}
+		if err := proto.Unmarshal(payload.GetData(), tx); err != nil {
+			return nil, err
+		}
+		for _, action := range tx.GetActions() {
+			actionKeys, err := actionAssetWrites(action, chaincode)
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, actionKeys...)
+		}
+	}
+	return keys, nil
+}
+
+// actionAssetWrites returns the asset keys one transaction action wrote
+func actionAssetWrites(action *peer.TransactionAction, chaincode string) ([]string, error) {
+	actionPayload := &peer.ChaincodeActionPayload{}
+	if err := proto.Unmarshal(action.GetPayload(), actionPayload); err != nil {
+		return nil, err
+	}
+	responsePayload := &peer.ProposalResponsePayload{}
+	if err := proto.Unmarshal(actionPayload.GetAction().GetProposalResponsePayload(), responsePayload); err != nil {
+		return nil, err
+	}
+	chaincodeAction := &peer.ChaincodeAction{}
+	if err := proto.Unmarshal(responsePayload.GetExtension(), chaincodeAction); err != nil {
+		return nil, err
+	}
+	txRWSet := &rwset.TxReadWriteSet{}
+	if err := proto.Unmarshal(chaincodeAction.GetResults(), txRWSet); err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	for _, nsRWSet := range txRWSet.GetNsRwset() {
+		if nsRWSet.GetNamespace() != chaincode {
+			continue
+		}
+		kvRWSet := &kvrwset.KVRWSet{}
+		if err := proto.Unmarshal(nsRWSet.GetRwset(), kvRWSet); err != nil {
+			return nil, err
+		}
+		for _, write := range kvRWSet.GetWrites() {
+			if key := write.GetKey(); key != "" && !strings.HasPrefix(key, "\x00") {
+				keys = append(keys, key)
+			}
+		}
+	}
+	return keys, nil
+}
+
+// readAssetsBatched reads the current state of the assets with ReadAssets, in
+// batches as large as the chaincode accepts
+func (h *ApiHandler) readAssetsBatched(r *http.Request, ids []string) (map[string]assetReadResult, error) {
+	results := make(map[string]assetReadResult, len(ids))
+	for start := 0; start < len(ids); start += maxBatchReadIDs {
+		end := start + maxBatchReadIDs
+		if end > len(ids) {
+			end = len(ids)
+		}
+		idsJSON, err := json.Marshal(ids[start:end])
+		if err != nil {
+			return nil, err
+		}
+
+		log.Printf("--> Evaluating Transaction: ReadAssets, Count: %d", end-start)
+		result, err := h.contract(r).EvaluateTransaction("ReadAssets", string(idsJSON))
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("<-- Transaction Evaluated: ReadAssets, Count: %d", end-start)
+
+		var batch map[string]assetReadResult
+		if err := json.Unmarshal(result, &batch); err != nil {
+			return nil, fmt.Errorf("failed to parse assets: %w", err)
+		}
+		for id, read := range batch {
+			results[id] = read
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/mux"
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+)
+
+// loadChannels parses FABRIC_CHANNELS, the allow-list of channels the API may address,
+// as comma separated channel or channel=chaincode entries (e.g. "mychannel,staging=asset-manager-dev").
+// The default channel is always allowed.
+func loadChannels() (map[string]string, error) {
+	channels := map[string]string{channelName: chaincodeName}
+
+	value := os.Getenv("FABRIC_CHANNELS")
+	if value == "" {
+		return channels, nil
+	}
+
+	for _, entry := range strings.Split(value, ",") {
+		channel, chaincode, found := strings.Cut(strings.TrimSpace(entry), "=")
+		if channel == "" || (found && chaincode == "") {
+			return nil, fmt.Errorf("invalid FABRIC_CHANNELS entry %q, expected channel or channel=chaincode", entry)
+		}
+		if !found {
+			chaincode = chaincodeName
+		}
+		channels[channel] = chaincode
+	}
+	return channels, nil
+}
+
+// requestChannel returns the channel from the /channels/{channel} prefix, or the default channel
+func requestChannel(r *http.Request) string {
+	if channel := mux.Vars(r)["channel"]; channel != "" {
+		return channel
+	}
+	return channelName
+}
+
+// channelMiddleware rejects requests for channels that aren't in the allow-list
+func (h *ApiHandler) channelMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		channel := requestChannel(r)
+		if _, ok := h.Channels[channel]; !ok {
+			writeError(w, http.StatusNotFound, codeUnknownChannel, fmt.Sprintf("Channel %q is not allowed", channel))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// contractCache keeps one contract per organization, channel and chaincode
+type contractCache struct {
+	mu        sync.Mutex
+	contracts map[string]*client.Contract
+}
+
+func newContractCache() *contractCache {
+	return &contractCache{
+		contracts: make(map[string]*client.Contract),
+	}
+}
+
+// clear drops every cached contract, for when the gateways are replaced
+func (c *contractCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.contracts = make(map[string]*client.Contract)
+}
+
+// get returns the cached contract, creating it from the org's gateway on first use
+func (c *contractCache) get(gw *client.Gateway, org string, channel string, chaincode string) *client.Contract {
+	key := org + "|" + channel + "|" + chaincode
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	contract, ok := c.contracts[key]
+	if !ok {
+		contract = gw.GetNetwork(channel).GetContract(chaincode)
+		c.contracts[key] = contract
+	}
+	return contract
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"compress/gzip"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// defaultCompressionMinBytes is the smallest response compressed when
+// COMPRESSION_MIN_BYTES is unset. Smaller bodies gain little from gzip.
+const defaultCompressionMinBytes = 1024
+
+// loadCompression reads RESPONSE_COMPRESSION and COMPRESSION_MIN_BYTES. It reports
+// whether responses are gzipped and the size a body must reach to be compressed.
+func loadCompression() (int, bool) {
+	enabled, _ := strconv.ParseBool(os.Getenv("RESPONSE_COMPRESSION"))
+	if !enabled {
+		return 0, false
+	}
+
+	minBytes := defaultCompressionMinBytes
+	if value := os.Getenv("COMPRESSION_MIN_BYTES"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			log.Printf("Ignoring invalid COMPRESSION_MIN_BYTES %q, using %d", value, defaultCompressionMinBytes)
+		} else {
+			minBytes = parsed
+		}
+	}
+	log.Printf("Response compression enabled for bodies of %d bytes or more", minBytes)
+	return minBytes, true
+}
+
+// compressionMiddleware gzips responses of at least minBytes for clients that
+// send Accept-Encoding: gzip. Streaming responses are compressed from their
+// first flush. WebSocket handshakes and HEAD requests are left alone.
+func compressionMiddleware(next http.Handler, minBytes int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead || r.Header.Get("Upgrade") != "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Add("Vary", "Accept-Encoding")
+		if !acceptsGzip(r.Header.Get("Accept-Encoding")) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		gw := &gzipWriter{ResponseWriter: w, minBytes: minBytes, status: http.StatusOK}
+		defer func() {
+			// A panicking handler's partial body is dropped rather than sent, so
+			// recoveryMiddleware can still answer with a 500
+			if recovered := recover(); recovered != nil {
+				gw.buffered = nil
+				panic(recovered)
+			}
+			gw.close()
+		}()
+		next.ServeHTTP(gw, r)
+	})
+}
+
+// acceptsGzip reports whether an Accept-Encoding header allows gzip
+func acceptsGzip(acceptEncoding string) bool {
+	for _, entry := range strings.Split(acceptEncoding, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(entry), ";")
+		if !strings.EqualFold(strings.TrimSpace(coding), "gzip") {
+			continue
+		}
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if weight, err := strconv.ParseFloat(q, 64); err == nil && weight == 0 {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// gzipWriter holds the start of the body back until it knows whether the
+// response is large enough to compress, then writes it plain or through gzip
+type gzipWriter struct {
+	http.ResponseWriter
+	minBytes int
+	status   int
+	buffered []byte
+	started  bool
+	gz       *gzip.Writer
+}
+
+// WriteHeader is delayed until the body decides on the encoding
+func (gw *gzipWriter) WriteHeader(status int) {
+	if !gw.started {
+		gw.status = status
+	}
+}
+
+func (gw *gzipWriter) Write(b []byte) (int, error) {
+	if gw.started {
+		if gw.gz != nil {
+			return gw.gz.Write(b)
+		}
+		return gw.ResponseWriter.Write(b)
+	}
+
+	gw.buffered = append(gw.buffered, b...)
+	if len(gw.buffered) >= gw.minBytes {
+		if err := gw.start(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// Flush starts compressing right away, so streamed responses reach the client
+func (gw *gzipWriter) Flush() {
+	if !gw.started {
+		gw.start(true)
+	}
+	if gw.gz != nil {
+		gw.gz.Flush()
+	}
+	if flusher, ok := gw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// start writes the headers and the buffered body, through gzip when compress is
+// set and the response may have a body that isn't encoded already
+func (gw *gzipWriter) start(compress bool) error {
+	gw.started = true
+
+	header := gw.Header()
+	if header.Get("Content-Type") == "" && len(gw.buffered) > 0 {
+		// Sniff the plain body, the server would otherwise sniff the gzip bytes
+		header.Set("Content-Type", http.DetectContentType(gw.buffered))
+	}
+	if header.Get("Content-Encoding") != "" || gw.status == http.StatusNoContent || gw.status == http.StatusNotModified {
+		compress = false
+	}
+	if compress {
+		header.Del("Content-Length")
+		header.Set("Content-Encoding", "gzip")
+		gw.gz = gzip.NewWriter(gw.ResponseWriter)
+	}
+	gw.ResponseWriter.WriteHeader(gw.status)
+
+	buffered := gw.buffered
+	gw.buffered = nil
+	if len(buffered) == 0 {
+		return nil
+	}
+	_, err := gw.Write(buffered)
+	return err
+}
+
+// close sends a body that stayed below minBytes as it is, or ends the gzip stream
+func (gw *gzipWriter) close() {
+	if !gw.started {
+		gw.start(false)
+	}
+	if gw.gz != nil {
+		gw.gz.Close()
+	}
+}
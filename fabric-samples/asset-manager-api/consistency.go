@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+)
+
+// Committed reads retry for a short while so peers that are a block or two
+// behind can catch up before the read is reported as inconsistent
+const (
+	committedReadAttempts = 5
+	committedReadBackoff  = 200 * time.Millisecond
+)
+
+// errPeersDisagree means the organizations' peers returned different results
+var errPeersDisagree = errors.New("peers of different organizations returned different results")
+
+// wantsCommittedRead reports whether the request asked for ?consistency=committed
+func wantsCommittedRead(r *http.Request) bool {
+	return r.URL.Query().Get("consistency") == "committed"
+}
+
+// evaluateCommitted evaluates a transaction on a peer of every enabled organization
+// and only returns once they all agree, so a write committed through any of
+// them is visible. The default single-peer evaluate is faster but may be stale.
+// Retries stop early when the client goes away.
+func (h *ApiHandler) evaluateCommitted(r *http.Request, name string, args ...string) ([]byte, error) {
+	contract := h.contract(r)
+
+	orgs := make([]string, 0, len(h.orgConns))
+	for mspID := range h.orgConns {
+		orgs = append(orgs, mspID)
+	}
+	sort.Strings(orgs)
+
+	for attempt := 1; ; attempt++ {
+		result, agreed, err := evaluateOnOrgs(contract, orgs, name, args...)
+		if agreed {
+			return result, err
+		}
+		if attempt == committedReadAttempts {
+			return nil, errPeersDisagree
+		}
+		log.Printf("Peers disagree on %s, retrying (attempt %d of %d)", name, attempt, committedReadAttempts)
+
+		timer := time.NewTimer(committedReadBackoff)
+		select {
+		case <-timer.C:
+		case <-r.Context().Done():
+			timer.Stop()
+			return nil, r.Context().Err()
+		}
+	}
+}
+
+// evaluateOnOrgs evaluates the transaction once per org and reports whether all
+// peers returned the same result, or all failed
+func evaluateOnOrgs(contract *client.Contract, orgs []string, name string, args ...string) ([]byte, bool, error) {
+	var firstResult []byte
+	var firstErr error
+	for i, org := range orgs {
+		proposal, err := contract.NewProposal(name, client.WithArguments(args...), client.WithEndorsingOrganizations(org))
+		if err != nil {
+			return nil, true, err
+		}
+		result, err := proposal.Evaluate()
+
+		if i == 0 {
+			firstResult, firstErr = result, err
+			continue
+		}
+		if (err == nil) != (firstErr == nil) || !bytes.Equal(result, firstResult) {
+			return nil, false, nil
+		}
+	}
+	return firstResult, true, firstErr
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// cryptoHint is printed with missing crypto material, which on a first run
+// almost always means the test-network isn't up or the API was started elsewhere
+const cryptoHint = "did you run the test-network (./network.sh up createChannel) and start the API from fabric-samples/asset-manager-api?"
+
+// validateCryptoMaterial checks that every file the enabled orgs need to connect
+// exists before any connection is made, and describes everything missing at once.
+// newIdentity and newSign still panic on files they can't parse.
+func validateCryptoMaterial(orgs []orgConfig) error {
+	var problems []string
+	for _, org := range orgs {
+		if problem := checkFile(org.CertPath); problem != "" {
+			problems = append(problems, fmt.Sprintf("%s: certificate %s", org.MSPID, problem))
+		}
+		if problem := checkKeystore(org.KeyPath); problem != "" {
+			problems = append(problems, fmt.Sprintf("%s: private key %s", org.MSPID, problem))
+		}
+
+		peers, err := orgPeers(org)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", org.MSPID, err))
+		}
+		// Insecure mode reads no TLS files at all
+		if insecureMode() {
+			continue
+		}
+		for _, peer := range peers {
+			if problem := checkFile(peer.TLSCertPath); problem != "" {
+				problems = append(problems, fmt.Sprintf("%s: TLS CA certificate of peer %s %s", org.MSPID, peer.Endpoint, problem))
+			}
+		}
+
+		if org.AdminFromEnv {
+			if problem := checkFile(org.AdminCertPath); problem != "" {
+				problems = append(problems, fmt.Sprintf("%s: admin certificate %s", org.MSPID, problem))
+			}
+			if problem := checkKeystore(org.AdminKeyPath); problem != "" {
+				problems = append(problems, fmt.Sprintf("%s: admin private key %s", org.MSPID, problem))
+			}
+		}
+
+		if org.TLSClientCertPath != "" {
+			if problem := checkFile(org.TLSClientCertPath); problem != "" {
+				problems = append(problems, fmt.Sprintf("%s: TLS client certificate %s", org.MSPID, problem))
+			}
+			if problem := checkFile(org.TLSClientKeyPath); problem != "" {
+				problems = append(problems, fmt.Sprintf("%s: TLS client key %s", org.MSPID, problem))
+			}
+		}
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("missing crypto material: %s; %s", strings.Join(problems, "; "), cryptoHint)
+}
+
+// checkFile describes what is wrong with a crypto file, or returns "" when it can be read
+func checkFile(p string) string {
+	resolved := displayPath(p)
+	info, err := os.Stat(networkPath(p))
+	switch {
+	case os.IsNotExist(err):
+		return "not found at " + resolved
+	case err != nil:
+		return fmt.Sprintf("cannot be read at %s: %v", resolved, err)
+	case info.IsDir():
+		return "expected a file but found a directory at " + resolved
+	}
+	return ""
+}
+
+// checkKeystore describes what is wrong with a keystore directory, which must hold
+// the org user's private key, or returns "" when it has one
+func checkKeystore(p string) string {
+	resolved := displayPath(p)
+	files, err := os.ReadDir(networkPath(p))
+	switch {
+	case os.IsNotExist(err):
+		return "directory not found at " + resolved
+	case err != nil:
+		return fmt.Sprintf("directory cannot be read at %s: %v", resolved, err)
+	case len(files) == 0:
+		return "not found, the keystore at " + resolved + " is empty"
+	}
+	return ""
+}
+
+// displayPath returns the absolute form of a crypto path, so the message shows
+// where the API actually looked
+func displayPath(p string) string {
+	resolved, err := filepath.Abs(networkPath(p))
+	if err != nil {
+		return networkPath(p)
+	}
+	return resolved
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// ExportAssetsCSVHandler handles GET /api/assets.csv
+// It writes every asset as a CSV row, decoding and writing one asset at a time
+// so the parsed ledger is never held in memory. MPIN is never exported.
+func (h *ApiHandler) ExportAssetsCSVHandler(w http.ResponseWriter, r *http.Request) {
+	log.Printf("--> Evaluating Transaction: GetAllAssets")
+	result, err := h.contract(r).EvaluateTransaction("GetAllAssets")
+	if err != nil {
+		writeFabricError(w, "Failed to evaluate transaction", err)
+		return
+	}
+	log.Printf("<-- Transaction Evaluated: GetAllAssets")
+
+	columns := h.csvColumns(r)
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", `attachment; filename="assets.csv"`)
+	writer := csv.NewWriter(w)
+	writer.Write(columns)
+
+	// An empty ledger comes back as an empty payload rather than []
+	if len(result) > 0 {
+		decoder := json.NewDecoder(bytes.NewReader(result))
+		if _, err := decoder.Token(); err != nil {
+			log.Printf("CSV export stopped, failed to parse assets: %s", err)
+			return
+		}
+		for decoder.More() {
+			var asset Asset
+			if err := decoder.Decode(&asset); err != nil {
+				// The response has already started, so all we can do is stop the stream
+				log.Printf("CSV export stopped, failed to parse asset: %s", err)
+				break
+			}
+			writer.Write(assetCSVRow(&asset, columns))
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		log.Printf("CSV export failed: %s", err)
+	}
+}
+
+// csvColumns returns the asset fields exported for the caller: the visible ones, never MPIN
+func (h *ApiHandler) csvColumns(r *http.Request) []string {
+	visible := h.visibleFields(callerRole(r))
+	var columns []string
+	for _, field := range assetFields {
+		if field == "MPIN" || (visible != nil && !visible[field]) {
+			continue
+		}
+		columns = append(columns, field)
+	}
+	return columns
+}
+
+// ExportAssetHistoryCSVHandler handles GET /api/assets/{id}/history.csv
+// It writes a row per history record: txId, timestamp, isDelete and the exported
+// asset fields. Histories longer than the chaincode's MaxHistoryRecords are
+// rejected with 422 rather than read without bound.
+func (h *ApiHandler) ExportAssetHistoryCSVHandler(w http.ResponseWriter, r *http.Request) {
+	assetID := normalizeDealerID(mux.Vars(r)["id"])
+	if !requireDealerID(w, assetID) {
+		return
+	}
+
+	records, truncated, maxRecords, err := h.readBoundedAssetHistory(r, assetID)
+	if err != nil {
+		writeFabricError(w, "Failed to evaluate transaction", err)
+		return
+	}
+	if truncated {
+		writeHistoryTooLarge(w, assetID, maxRecords)
+		return
+	}
+
+	columns := h.csvColumns(r)
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-history.csv"`, assetID))
+	writer := csv.NewWriter(w)
+	writer.Write(append([]string{"txId", "timestamp", "isDelete"}, columns...))
+	for _, record := range records {
+		asset := record.Record
+		if asset == nil {
+			asset = &Asset{DEALERID: assetID}
+		}
+		row := []string{record.TxID, record.Timestamp.UTC().Format(time.RFC3339Nano), strconv.FormatBool(record.IsDelete)}
+		writer.Write(append(row, assetCSVRow(asset, columns)...))
+	}
+	writer.Flush()
+
+	if err := writer.Error(); err != nil {
+		log.Printf("History CSV export failed: %s", err)
+	}
+}
+
+// assetCSVRow returns the asset's values for the given columns
+func assetCSVRow(asset *Asset, columns []string) []string {
+	values := map[string]string{
+		"DEALERID":         asset.DEALERID,
+		"MSISDN":           asset.MSISDN,
+		"MPIN":             asset.MPIN,
+		"BALANCE":          asset.BALANCE,
+		"STATUS":           asset.STATUS,
+		"TRANSAMOUNT":      asset.TRANSAMOUNT,
+		"TRANSTYPE":        asset.TRANSTYPE,
+		"REMARKS":          asset.REMARKS,
+		"Currency":         asset.Currency,
+		"LastTransferTxID": asset.LastTransferTxID,
+		"CreatedAt":        asset.CreatedAt,
+		"LastModifiedBy":   asset.LastModifiedBy,
+	}
+	if asset.SchemaVersion != 0 {
+		values["SchemaVersion"] = strconv.Itoa(asset.SchemaVersion)
+	}
+	if len(asset.Metadata) > 0 {
+		// A JSON object, encoding/json sorts its keys
+		metadataJSON, _ := json.Marshal(asset.Metadata)
+		values["Metadata"] = string(metadataJSON)
+	}
+
+	row := make([]string, len(columns))
+	for i, column := range columns {
+		row[i] = values[column]
+	}
+	return row
+}
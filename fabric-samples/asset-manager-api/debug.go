@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+	"github.com/hyperledger/fabric-protos-go-apiv2/common"
+	"github.com/hyperledger/fabric-protos-go-apiv2/gateway"
+	"github.com/hyperledger/fabric-protos-go-apiv2/msp"
+	"github.com/hyperledger/fabric-protos-go-apiv2/peer"
+	"google.golang.org/protobuf/proto"
+)
+
+// endorsementReport describes an endorsed but uncommitted transaction
+type endorsementReport struct {
+	TxID         string          `json:"txId"`
+	Status       int32           `json:"status"`
+	Message      string          `json:"message"`
+	Result       string          `json:"result"`
+	Endorsements []endorserEntry `json:"endorsements"`
+}
+
+// endorserEntry identifies one peer that signed the proposal response
+type endorserEntry struct {
+	MSPID       string `json:"mspId"`
+	Certificate string `json:"certificate"`
+}
+
+// DebugEndorseHandler handles POST /api/debug/endorse
+// with a {"transaction": "...", "args": [...]} body. It endorses the proposal,
+// honoring X-Endorsing-Orgs, and reports the chaincode response and who endorsed
+// it without ever submitting the transaction. When endorsement fails, the usual
+// error envelope lists each peer's error, including response mismatches.
+func (h *ApiHandler) DebugEndorseHandler(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Transaction string   `json:"transaction"`
+		Args        []string `json:"args"`
+	}
+	if err := decodeJSONBody(r, &request); err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, err.Error())
+		return
+	}
+	if request.Transaction == "" {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "transaction is required")
+		return
+	}
+
+	options := []client.ProposalOption{
+		client.WithArguments(request.Args...),
+		client.WithTransient(saltedTransient(nil)),
+	}
+	if orgs, ok := r.Context().Value(endorsingOrgsContextKey).([]string); ok {
+		options = append(options, client.WithEndorsingOrganizations(orgs...))
+	}
+	proposal, err := h.contract(r).NewProposal(request.Transaction, options...)
+	if err != nil {
+		writeFabricError(w, "Failed to create proposal", err)
+		return
+	}
+
+	log.Printf("--> Endorsing for debug: %s, TxID: %s", request.Transaction, proposal.TransactionID())
+	transaction, err := proposal.Endorse()
+	if err != nil {
+		writeFabricError(w, "Failed to endorse proposal", err)
+		return
+	}
+	log.Printf("<-- Endorsed for debug, not submitted: %s, TxID: %s", request.Transaction, transaction.TransactionID())
+
+	report, err := newEndorsementReport(transaction)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, codeInternalError, fmt.Sprintf("Failed to parse endorsed transaction: %s", err))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// newEndorsementReport unpacks the chaincode response and endorsements from an
+// endorsed transaction envelope
+func newEndorsementReport(transaction *client.Transaction) (*endorsementReport, error) {
+	transactionBytes, err := transaction.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	prepared := &gateway.PreparedTransaction{}
+	if err := proto.Unmarshal(transactionBytes, prepared); err != nil {
+		return nil, err
+	}
+
+	payload := &common.Payload{}
+	if err := proto.Unmarshal(prepared.GetEnvelope().GetPayload(), payload); err != nil {
+		return nil, err
+	}
+	tx := &peer.Transaction{}
+	if err := proto.Unmarshal(payload.GetData(), tx); err != nil {
+		return nil, err
+	}
+	if len(tx.GetActions()) == 0 {
+		return nil, fmt.Errorf("the transaction has no actions")
+	}
+	actionPayload := &peer.ChaincodeActionPayload{}
+	if err := proto.Unmarshal(tx.GetActions()[0].GetPayload(), actionPayload); err != nil {
+		return nil, err
+	}
+
+	responsePayload := &peer.ProposalResponsePayload{}
+	if err := proto.Unmarshal(actionPayload.GetAction().GetProposalResponsePayload(), responsePayload); err != nil {
+		return nil, err
+	}
+	chaincodeAction := &peer.ChaincodeAction{}
+	if err := proto.Unmarshal(responsePayload.GetExtension(), chaincodeAction); err != nil {
+		return nil, err
+	}
+
+	report := &endorsementReport{
+		TxID:         transaction.TransactionID(),
+		Status:       chaincodeAction.GetResponse().GetStatus(),
+		Message:      chaincodeAction.GetResponse().GetMessage(),
+		Result:       string(transaction.Result()),
+		Endorsements: []endorserEntry{},
+	}
+	for _, endorsement := range actionPayload.GetAction().GetEndorsements() {
+		endorser := &msp.SerializedIdentity{}
+		if err := proto.Unmarshal(endorsement.GetEndorser(), endorser); err != nil {
+			return nil, err
+		}
+		report.Endorsements = append(report.Endorsements, endorserEntry{
+			MSPID:       endorser.GetMspid(),
+			Certificate: string(endorser.GetIdBytes()),
+		})
+	}
+	return report, nil
+}
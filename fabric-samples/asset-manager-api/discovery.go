@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-protos-go-apiv2/discovery"
+	"github.com/hyperledger/fabric-protos-go-apiv2/gossip"
+	"github.com/hyperledger/fabric-protos-go-apiv2/msp"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+)
+
+// discoveryTimeout bounds the discovery query made for each org at startup
+const discoveryTimeout = 10 * time.Second
+
+// discoveryEnabled reports whether FABRIC_DISCOVERY is set to true.
+//
+// The gateway peer always uses discovery to pick endorsers that satisfy the
+// chaincode's endorsement policy. This option also discovers which peers of the
+// org are alive on the default channel, so only one of them has to be configured
+// and the rest are used for failover.
+func discoveryEnabled() bool {
+	enabled, _ := strconv.ParseBool(os.Getenv("FABRIC_DISCOVERY"))
+	return enabled
+}
+
+// discoveredPeers is the org's view of the channel returned by the discovery service
+type discoveredPeers struct {
+	endpoints    []string
+	tlsRootCerts [][]byte
+}
+
+// withDiscoveredPeers replaces conn with a failover connection over the configured
+// peers followed by the org's other peers found through discovery. When discovery
+// fails or finds nothing new, conn is kept.
+func withDiscoveredPeers(conn *grpc.ClientConn, org orgConfig, configured []peerConfig) *grpc.ClientConn {
+	discovered, err := discoverPeers(conn, org, channelName)
+	if err != nil {
+		log.Printf("Peer discovery for %s failed, using the configured peers: %v", org.MSPID, err)
+		return conn
+	}
+
+	peers := append([]peerConfig{}, configured...)
+	known := make(map[string]bool, len(peers))
+	for _, peer := range peers {
+		known[peer.Endpoint] = true
+	}
+	for _, endpoint := range discovered.endpoints {
+		if known[endpoint] {
+			continue
+		}
+		known[endpoint] = true
+		host, _, _ := strings.Cut(endpoint, ":")
+		peers = append(peers, peerConfig{Endpoint: endpoint, HostOverride: host})
+	}
+	if len(peers) == len(configured) {
+		log.Printf("Peer discovery for %s found no peers besides the configured ones", org.MSPID)
+		return conn
+	}
+
+	creds, err := peerCredentials(org, configured, "", discovered.tlsRootCerts...)
+	if err != nil {
+		panic(err)
+	}
+
+	discoveredConn, err := newFailoverConnection(org, peers, creds)
+	if err != nil {
+		log.Printf("Failed to connect to the discovered peers of %s, using the configured peers: %v", org.MSPID, err)
+		return conn
+	}
+	conn.Close()
+
+	endpoints := make([]string, len(peers))
+	for i, peer := range peers {
+		endpoints[i] = peer.Endpoint
+	}
+	log.Printf("Discovered peers for %s on channel %s: %s", org.MSPID, channelName, strings.Join(endpoints, ", "))
+	return discoveredConn
+}
+
+// discoverPeers asks the discovery service of the peer behind conn for the org's
+// peers on the channel and the TLS root certificates of the org
+func discoverPeers(conn *grpc.ClientConn, org orgConfig, channel string) (*discoveredPeers, error) {
+	id := newIdentity(org)
+	sign := newSign(org)
+
+	creator, err := proto.Marshal(&msp.SerializedIdentity{Mspid: id.MspID(), IdBytes: id.Credentials()})
+	if err != nil {
+		return nil, err
+	}
+	certificateHash, err := tlsClientCertificateHash(org)
+	if err != nil {
+		return nil, err
+	}
+
+	request, err := proto.Marshal(&discovery.Request{
+		Authentication: &discovery.AuthInfo{
+			ClientIdentity:    creator,
+			ClientTlsCertHash: certificateHash,
+		},
+		Queries: []*discovery.Query{
+			{Channel: channel, Query: &discovery.Query_ConfigQuery{ConfigQuery: &discovery.ConfigQuery{}}},
+			{Channel: channel, Query: &discovery.Query_PeerQuery{PeerQuery: &discovery.PeerMembershipQuery{}}},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	digest := sha256.Sum256(request)
+	signature, err := sign(digest[:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign discovery request: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), discoveryTimeout)
+	defer cancel()
+	response, err := discovery.NewDiscoveryClient(conn).Discover(ctx, &discovery.SignedRequest{Payload: request, Signature: signature})
+	if err != nil {
+		return nil, err
+	}
+
+	discovered := &discoveredPeers{}
+	for _, result := range response.GetResults() {
+		if queryErr := result.GetError(); queryErr != nil {
+			return nil, fmt.Errorf("discovery query failed: %s", queryErr.GetContent())
+		}
+		if config := result.GetConfigResult(); config != nil {
+			mspConfig := config.GetMsps()[org.MSPID]
+			discovered.tlsRootCerts = append(discovered.tlsRootCerts, mspConfig.GetTlsRootCerts()...)
+			discovered.tlsRootCerts = append(discovered.tlsRootCerts, mspConfig.GetTlsIntermediateCerts()...)
+		}
+		if members := result.GetMembers(); members != nil {
+			for _, peer := range members.GetPeersByOrg()[org.MSPID].GetPeers() {
+				endpoint, err := peerEndpoint(peer)
+				if err != nil {
+					return nil, err
+				}
+				if endpoint != "" {
+					discovered.endpoints = append(discovered.endpoints, endpoint)
+				}
+			}
+		}
+	}
+	return discovered, nil
+}
+
+// peerEndpoint returns the external endpoint a discovered peer advertises over gossip
+func peerEndpoint(peer *discovery.Peer) (string, error) {
+	var message gossip.GossipMessage
+	if err := proto.Unmarshal(peer.GetMembershipInfo().GetPayload(), &message); err != nil {
+		return "", fmt.Errorf("failed to parse peer membership: %w", err)
+	}
+	return message.GetAliveMsg().GetMembership().GetEndpoint(), nil
+}
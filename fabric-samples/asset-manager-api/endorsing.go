@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+	"github.com/hyperledger/fabric-protos-go-apiv2/peer"
+)
+
+// endorsingOrgsHeader lets a client pick which organizations endorse its submits,
+// e.g. "X-Endorsing-Orgs: Org1MSP,Org2MSP"
+const endorsingOrgsHeader = "X-Endorsing-Orgs"
+
+const endorsingOrgsContextKey contextKey = "endorsingOrgs"
+
+// loadEndorsingOrgs parses ALLOWED_ENDORSING_ORGS, the comma separated MSP IDs
+// clients may target with X-Endorsing-Orgs. It defaults to every known org.
+func loadEndorsingOrgs() (map[string]bool, error) {
+	allowed := make(map[string]bool)
+
+	value := os.Getenv("ALLOWED_ENDORSING_ORGS")
+	if value == "" {
+		for mspID := range knownOrgs {
+			allowed[mspID] = true
+		}
+		return allowed, nil
+	}
+
+	for _, mspID := range strings.Split(value, ",") {
+		mspID = strings.TrimSpace(mspID)
+		if mspID == "" {
+			continue
+		}
+		if _, ok := knownOrgs[mspID]; !ok {
+			return nil, fmt.Errorf("unknown organization %q in ALLOWED_ENDORSING_ORGS", mspID)
+		}
+		allowed[mspID] = true
+	}
+	return allowed, nil
+}
+
+// endorsingOrgsMiddleware validates X-Endorsing-Orgs against the allow-list
+// and stores the requested orgs for submitTransaction
+func (h *ApiHandler) endorsingOrgsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		value := r.Header.Get(endorsingOrgsHeader)
+		if value == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		var orgs []string
+		for _, mspID := range strings.Split(value, ",") {
+			mspID = strings.TrimSpace(mspID)
+			if mspID == "" {
+				continue
+			}
+			if !h.EndorsingOrgs[mspID] {
+				writeError(w, http.StatusBadRequest, codeInvalidRequest, fmt.Sprintf("organization %q may not be targeted with %s", mspID, endorsingOrgsHeader))
+				return
+			}
+			orgs = append(orgs, mspID)
+		}
+		if len(orgs) == 0 {
+			writeError(w, http.StatusBadRequest, codeInvalidRequest, endorsingOrgsHeader+" must list at least one organization")
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), endorsingOrgsContextKey, orgs)))
+	})
+}
+
+// submitTransaction submits a transaction on the request's contract, endorsed
+// by the orgs from X-Endorsing-Orgs when the client picked any. X-Async
+// requests return as soon as the transaction reaches the orderer, others are
+// retried once when they fail to commit because of a read conflict.
+func (h *ApiHandler) submitTransaction(r *http.Request, name string, args ...string) ([]byte, error) {
+	return h.submitWithTransient(r, name, nil, args...)
+}
+
+// submitWithTransient is submitTransaction with private inputs, such as the MPIN,
+// passed in the transient map so they never become transaction arguments
+func (h *ApiHandler) submitWithTransient(r *http.Request, name string, transient map[string][]byte, args ...string) ([]byte, error) {
+	options := []client.ProposalOption{
+		client.WithArguments(args...),
+		client.WithTransient(saltedTransient(transient)),
+	}
+	if orgs, ok := r.Context().Value(endorsingOrgsContextKey).([]string); ok {
+		options = append(options, client.WithEndorsingOrganizations(orgs...))
+	}
+	if submission, ok := r.Context().Value(asyncContextKey).(*asyncSubmission); ok {
+		result, err := h.submitAsync(h.contract(r), submission, name, options...)
+		if err != nil {
+			h.audit(r, name, args, transactionID(err), auditFailed, err)
+			return nil, err
+		}
+		h.audit(r, name, args, submission.txID, auditSubmitted, nil)
+		return result, nil
+	}
+
+	result, txID, err := h.submitAndWait(r, name, options...)
+	if code, retryable := retryableCommitFailure(err); retryable {
+		// A read conflict only means another transaction got there first, so the
+		// whole submit is run again once against the new state
+		log.Printf("Transaction %s, TxID: %s, failed with %s, retrying in %s", name, txID, code, commitRetryDelay)
+		h.audit(r, name, args, txID, auditFailed, err)
+
+		select {
+		case <-time.After(commitRetryDelay):
+		case <-r.Context().Done():
+			return nil, err
+		}
+		result, txID, err = h.submitAndWait(r, name, options...)
+	}
+	if err != nil {
+		h.audit(r, name, args, txID, auditFailed, err)
+		return nil, err
+	}
+	h.audit(r, name, args, txID, auditCommitted, nil)
+	return result, nil
+}
+
+// commitRetryDelay is how long a submit that failed with a read conflict waits
+// before it is run again
+const commitRetryDelay = 500 * time.Millisecond
+
+// retryableCommitFailure reports whether err is a transaction that was ordered but
+// invalidated by a read conflict with a concurrent transaction. Running it again
+// reads the new state, so it can succeed. Other validation failures are final.
+func retryableCommitFailure(err error) (peer.TxValidationCode, bool) {
+	var commitErr *client.CommitError
+	if !errors.As(err, &commitErr) {
+		return 0, false
+	}
+	switch commitErr.Code {
+	case peer.TxValidationCode_MVCC_READ_CONFLICT, peer.TxValidationCode_PHANTOM_READ_CONFLICT:
+		return commitErr.Code, true
+	}
+	return commitErr.Code, false
+}
+
+// submitAndWait is Contract.Submit, unrolled so the audit log gets the ID of a
+// committed transaction. The ID is also returned with errors where it is known.
+func (h *ApiHandler) submitAndWait(r *http.Request, name string, options ...client.ProposalOption) ([]byte, string, error) {
+	result, commit, err := h.contract(r).SubmitAsync(name, options...)
+	if err != nil {
+		return nil, transactionID(err), err
+	}
+	commitStatus, err := commit.Status()
+	if err != nil {
+		return nil, commit.TransactionID(), err
+	}
+	h.Metrics.recordCommit(commitStatus.Code)
+	if !commitStatus.Successful {
+		return nil, commitStatus.TransactionID, newCommitError(commitStatus.TransactionID, commitStatus.Code)
+	}
+	return result, commitStatus.TransactionID, nil
+}
+
+// newCommitError matches the error Contract.Submit returns for a transaction that
+// failed validation. CommitError's message is unexported, so it is wrapped in one
+// with the same text and still found by errors.As.
+func newCommitError(txID string, code peer.TxValidationCode) error {
+	return fmt.Errorf("transaction %s failed to commit with status code %d (%s)%w",
+		txID, int32(code), code.String(), &client.CommitError{TransactionID: txID, Code: code})
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+	"github.com/hyperledger/fabric-protos-go-apiv2/gateway"
+	"google.golang.org/grpc/status"
+)
+
+// Machine-readable error codes returned in the error envelope.
+// Clients can branch on these, so existing values must not change.
+const (
+	codeInvalidRequest       = "INVALID_REQUEST"
+	codeUnauthorized         = "UNAUTHORIZED"
+	codeForbidden            = "FORBIDDEN"
+	codeUnknownOrganization  = "UNKNOWN_ORGANIZATION"
+	codeUnknownChannel       = "UNKNOWN_CHANNEL"
+	codeAssetNotFound        = "ASSET_NOT_FOUND"
+	codeAssetAlreadyExists   = "ASSET_ALREADY_EXISTS"
+	codeAssetFrozen          = "ASSET_FROZEN"
+	codeAssetNotFrozen       = "ASSET_NOT_FROZEN"
+	codeBalanceMismatch      = "BALANCE_MISMATCH"
+	codeInsufficientBalance  = "INSUFFICIENT_BALANCE"
+	codeCurrencyMismatch     = "CURRENCY_MISMATCH"
+	codeTransactionNotFound  = "TRANSACTION_NOT_FOUND"
+	codeRequestInProgress    = "REQUEST_IN_PROGRESS"
+	codeTooManyAttempts      = "TOO_MANY_ATTEMPTS"
+	codeReadOnly             = "READ_ONLY"
+	codeInconsistentRead     = "INCONSISTENT_READ"
+	codeTransactionFailed    = "TRANSACTION_FAILED"
+	codeOverloaded           = "OVERLOADED"
+	codeRichQueryUnsupported = "RICH_QUERY_UNSUPPORTED"
+	codeHistoryTooLarge      = "HISTORY_TOO_LARGE"
+	codeInternalError        = "INTERNAL_ERROR"
+)
+
+// errorEnvelope is the JSON body of every error response:
+// {"error":{"code":"ASSET_NOT_FOUND","message":"...","txId":"..."}}
+type errorEnvelope struct {
+	Error errorBody `json:"error"`
+}
+
+type errorBody struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	TxID    string `json:"txId,omitempty"`
+
+	// Set for failed Fabric transactions
+	Stage          string        `json:"stage,omitempty"`
+	ValidationCode string        `json:"validationCode,omitempty"`
+	Details        []errorDetail `json:"details,omitempty"`
+
+	// Set when a request body fails schema validation, one entry per problem
+	Fields []fieldError `json:"fields,omitempty"`
+}
+
+// fieldError is one invalid field of a request body
+type fieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// errorDetail is the error a single peer reported, which tells which
+// orgs failed to endorse when the endorsement policy isn't met
+type errorDetail struct {
+	Address string `json:"address,omitempty"`
+	MSPID   string `json:"mspId,omitempty"`
+	Message string `json:"message"`
+}
+
+// Stages of a Fabric transaction, reported in the error envelope
+const (
+	stageEndorse      = "ENDORSE"
+	stageSubmit       = "SUBMIT"
+	stageCommitStatus = "COMMIT_STATUS"
+	stageCommit       = "COMMIT"
+)
+
+// writeError writes an error envelope with the given status and code
+func writeError(w http.ResponseWriter, status int, code string, msg string) {
+	writeErrorWithTxID(w, status, code, msg, "")
+}
+
+// writeErrorWithTxID writes an error envelope that references a Fabric transaction
+func writeErrorWithTxID(w http.ResponseWriter, status int, code string, msg string, txID string) {
+	writeErrorBody(w, status, errorBody{
+		Code:    code,
+		Message: msg,
+		TxID:    txID,
+	})
+}
+
+// writeErrorBody writes an error envelope around body
+func writeErrorBody(w http.ResponseWriter, status int, body errorBody) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(errorEnvelope{Error: body})
+}
+
+// writeFabricError writes an error envelope for a failed evaluate or submit,
+// mapping well-known chaincode errors to their own status and code
+func writeFabricError(w http.ResponseWriter, prefix string, err error) {
+	status, code := http.StatusInternalServerError, codeTransactionFailed
+
+	messages := strings.Join(fabricErrorMessages(err), "; ")
+	message := fmt.Sprintf("%s: %s", prefix, messages)
+	switch {
+	case isNotFoundError(err):
+		status, code = http.StatusNotFound, codeAssetNotFound
+	case strings.Contains(messages, "already exists"):
+		status, code = http.StatusConflict, codeAssetAlreadyExists
+	case strings.Contains(messages, "is frozen"):
+		status, code = http.StatusConflict, codeAssetFrozen
+	case strings.Contains(messages, "is not frozen"):
+		// UnfreezeAsset on an asset that isn't frozen
+		status, code = http.StatusConflict, codeAssetNotFrozen
+	case strings.Contains(messages, "does not match the expected balance"):
+		status, code = http.StatusConflict, codeBalanceMismatch
+	case strings.Contains(messages, "insufficient balance"):
+		// Any write that would leave BALANCE below the chaincode's minimum
+		status, code = http.StatusConflict, codeInsufficientBalance
+	case strings.Contains(messages, "without a conversion rate"):
+		// A transfer between assets in different currencies
+		status, code = http.StatusConflict, codeCurrencyMismatch
+	case strings.Contains(messages, "not supported for leveldb"):
+		// Chaincode without a LevelDB fallback for the rich query it ran
+		status, code = http.StatusNotImplemented, codeRichQueryUnsupported
+		message = fmt.Sprintf("%s: the query requires CouchDB as the peer's state database, the peer uses LevelDB: %s", prefix, messages)
+	}
+
+	body := errorBody{
+		Code:    code,
+		Message: message,
+		TxID:    transactionID(err),
+		Details: fabricErrorDetails(err),
+	}
+
+	var commitErr *client.CommitError
+	if errors.As(err, &commitErr) {
+		body.ValidationCode = commitErr.Code.String()
+	}
+	body.Stage = transactionStage(err)
+
+	writeErrorBody(w, status, body)
+}
+
+// isNotFoundError reports whether a failed evaluate or submit was rejected by the
+// chaincode because the asset does not exist
+func isNotFoundError(err error) bool {
+	return strings.Contains(strings.Join(fabricErrorMessages(err), "; "), "does not exist")
+}
+
+// fabricErrorDetails returns the errors reported by individual peers
+func fabricErrorDetails(err error) []errorDetail {
+	var details []errorDetail
+	for _, detail := range status.Convert(err).Details() {
+		if detail, ok := detail.(*gateway.ErrorDetail); ok {
+			details = append(details, errorDetail{
+				Address: detail.GetAddress(),
+				MSPID:   detail.GetMspId(),
+				Message: detail.GetMessage(),
+			})
+		}
+	}
+	return details
+}
+
+// transactionStage returns which step of the transaction flow failed, or "" if
+// the error didn't come from the gateway
+func transactionStage(err error) string {
+	var endorseErr *client.EndorseError
+	var submitErr *client.SubmitError
+	var commitStatusErr *client.CommitStatusError
+	var commitErr *client.CommitError
+
+	switch {
+	case errors.As(err, &endorseErr):
+		return stageEndorse
+	case errors.As(err, &submitErr):
+		return stageSubmit
+	case errors.As(err, &commitStatusErr):
+		return stageCommitStatus
+	case errors.As(err, &commitErr):
+		return stageCommit
+	}
+	return ""
+}
+
+// fabricErrorMessages returns the error message followed by any messages the
+// peers attached as details, which is where chaincode errors end up on submit
+func fabricErrorMessages(err error) []string {
+	messages := []string{err.Error()}
+	for _, detail := range status.Convert(err).Details() {
+		if detail, ok := detail.(*gateway.ErrorDetail); ok {
+			messages = append(messages, detail.GetMessage())
+		}
+	}
+	return messages
+}
+
+// transactionID returns the Fabric transaction ID carried by a gateway error, if any
+func transactionID(err error) string {
+	var endorseErr *client.EndorseError
+	var submitErr *client.SubmitError
+	var commitStatusErr *client.CommitStatusError
+	var commitErr *client.CommitError
+
+	switch {
+	case errors.As(err, &endorseErr):
+		return endorseErr.TransactionID
+	case errors.As(err, &submitErr):
+		return submitErr.TransactionID
+	case errors.As(err, &commitStatusErr):
+		return commitStatusErr.TransactionID
+	case errors.As(err, &commitErr):
+		return commitErr.TransactionID
+	}
+	return ""
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// queryDealerIDs returns the dealers named by ?dealerId=, which may be repeated
+// or hold a comma separated list
+func queryDealerIDs(r *http.Request) []string {
+	var dealerIDs []string
+	for _, value := range r.URL.Query()["dealerId"] {
+		for _, dealerID := range strings.Split(value, ",") {
+			if dealerID = normalizeDealerID(dealerID); dealerID != "" {
+				dealerIDs = append(dealerIDs, dealerID)
+			}
+		}
+	}
+	return dealerIDs
+}
+
+// AssetEventsStreamHandler handles GET /api/events
+// It streams the AssetEvent and TransferEvent notifications of the request's
+// channel as server-sent events, each with the event name and a data line
+// shaped like the WebSocket messages. ?dealerId=D1&dealerId=D2 only sends events
+// for those dealers. A comment line is sent every 30s to keep the connection open.
+func (h *ApiHandler) AssetEventsStreamHandler(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, codeInternalError, "streaming is not supported by this connection")
+		return
+	}
+
+	channel := requestChannel(r)
+	network := h.gateway(h.requestOrg(r)).GetNetwork(channel)
+
+	// The subscription lives as long as the request
+	ctx := r.Context()
+	events, err := network.ChaincodeEvents(ctx, h.Channels[channel])
+	if err != nil {
+		writeFabricError(w, "Failed to subscribe to chaincode events", err)
+		return
+	}
+
+	filter := &wsFilter{}
+	filter.set(queryDealerIDs(r))
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	log.Printf("Event stream client subscribed to events on channel %s", channel)
+
+	ticker := time.NewTicker(wsPingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			log.Printf("Event stream client on channel %s disconnected", channel)
+			return
+		case <-ticker.C:
+			if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			if event.EventName != assetEventName && event.EventName != transferEventName {
+				continue
+			}
+			if !filter.matches(eventDealerIDs(event)) {
+				continue
+			}
+
+			data, err := json.Marshal(wsEvent{
+				BlockNumber: event.BlockNumber,
+				TxID:        event.TransactionID,
+				EventName:   event.EventName,
+				Payload:     event.Payload,
+			})
+			if err != nil {
+				log.Printf("Event stream failed to encode event: %v", err)
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "event: %s\nid: %s\ndata: %s\n\n", event.EventName, event.TransactionID, data); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+)
+
+// ExportHistoryHandler handles GET /api/export
+// It streams the history of every asset (or just ?id=) as newline-delimited JSON,
+// one history record per line, flushing after each asset to keep memory bounded
+func (h *ApiHandler) ExportHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	contract := h.contract(r)
+
+	var assetIDs []string
+	if assetID := normalizeDealerID(r.URL.Query().Get("id")); assetID != "" {
+		assetIDs = []string{assetID}
+	} else {
+		log.Printf("--> Evaluating Transaction: GetAllAssets")
+		result, err := contract.EvaluateTransaction("GetAllAssets")
+		if err != nil {
+			writeFabricError(w, "Failed to evaluate transaction", err)
+			return
+		}
+		log.Printf("<-- Transaction Evaluated: GetAllAssets")
+
+		// Only the keys are needed, the history carries the full records
+		var assets []struct {
+			DEALERID string `json:"DEALERID"`
+		}
+		if len(result) > 0 {
+			if err := json.Unmarshal(result, &assets); err != nil {
+				writeError(w, http.StatusInternalServerError, codeInternalError, fmt.Sprintf("Failed to parse assets: %s", err))
+				return
+			}
+		}
+		for _, asset := range assets {
+			assetIDs = append(assetIDs, asset.DEALERID)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	flusher, _ := w.(http.Flusher)
+	visible := h.visibleFields(callerRole(r))
+
+	for _, assetID := range assetIDs {
+		result, err := contract.EvaluateTransaction("GetAssetHistory", assetID)
+		if err != nil {
+			// The response has already started, so all we can do is stop the stream
+			log.Printf("Export stopped, failed to get history for %s: %s", assetID, err)
+			return
+		}
+
+		var records []json.RawMessage
+		if len(result) > 0 {
+			if err := json.Unmarshal(result, &records); err != nil {
+				log.Printf("Export stopped, failed to parse history for %s: %s", assetID, err)
+				return
+			}
+		}
+
+		for _, record := range records {
+			if visible != nil {
+				masked, err := maskRawHistoryRecord(record, visible)
+				if err != nil {
+					log.Printf("Export stopped, failed to mask history for %s: %s", assetID, err)
+					return
+				}
+				record = masked
+			}
+			w.Write(record)
+			w.Write([]byte("\n"))
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+}
+
+// maskRawHistoryRecord masks the asset inside a single JSON history record
+func maskRawHistoryRecord(record json.RawMessage, visible map[string]bool) (json.RawMessage, error) {
+	var fields map[string]any
+	if err := json.Unmarshal(record, &fields); err != nil {
+		return nil, err
+	}
+	maskHistoryRecord(fields, visible)
+	return json.Marshal(fields)
+}
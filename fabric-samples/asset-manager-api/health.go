@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// readinessTimeout bounds how long /readyz waits for each peer
+const readinessTimeout = 5 * time.Second
+
+// probePaths are served without an API key so Kubernetes probes don't need one
+var probePaths = map[string]bool{
+	"/livez":  true,
+	"/readyz": true,
+}
+
+// registerProbes adds the Kubernetes probe endpoints and /metrics to r. They only
+// exist at the top level, not per channel, since they describe the process rather
+// than a ledger.
+func (h *ApiHandler) registerProbes(r *mux.Router) {
+	r.HandleFunc("/livez", LivezHandler).Methods("GET")
+	r.HandleFunc("/readyz", h.ReadyzHandler).Methods("GET")
+	r.HandleFunc(metricsPath, h.MetricsHandler).Methods("GET")
+}
+
+// LivezHandler handles GET /livez
+// It only shows the process can serve requests, so a peer outage never gets the pod restarted
+func LivezHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+}
+
+// ReadyzHandler handles GET /readyz
+// It evaluates GetVersion through every org's gateway on the default channel and
+// returns 503 if any peer can't be reached, taking the pod out of load balancing
+func (h *ApiHandler) ReadyzHandler(w http.ResponseWriter, r *http.Request) {
+	orgs := make([]string, 0, len(h.orgConns))
+	for org := range h.orgConns {
+		orgs = append(orgs, org)
+	}
+	sort.Strings(orgs)
+
+	ready := true
+	peers := make(map[string]string, len(orgs))
+	for _, org := range orgs {
+		contract := h.contractFor(org, channelName, h.Channels[channelName])
+
+		ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+		_, err := contract.EvaluateWithContext(ctx, "GetVersion")
+		cancel()
+
+		// Probes are unauthenticated, so the error itself only goes to the log
+		if err != nil {
+			ready = false
+			peers[org] = "unavailable"
+			log.Printf("Readiness check failed for %s: %s", org, err)
+		} else {
+			peers[org] = "ok"
+		}
+	}
+
+	status, statusText := http.StatusOK, "ok"
+	if !ready {
+		status, statusText = http.StatusServiceUnavailable, "unavailable"
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]any{"status": statusText, "peers": peers})
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// idempotencyHeader lets clients safely retry a create
+const idempotencyHeader = "Idempotency-Key"
+
+// idempotencyStore remembers the response to recently seen idempotency keys
+type idempotencyStore struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]*idempotentResponse
+}
+
+// idempotentResponse is a stored response. It is pending while the first
+// request with the key is still being processed.
+type idempotentResponse struct {
+	pending bool
+	status  int
+	header  http.Header
+	body    []byte
+	expires time.Time
+}
+
+// newIdempotencyStore creates a store whose keys expire after IDEMPOTENCY_TTL (default 10m)
+func newIdempotencyStore() *idempotencyStore {
+	ttl := 10 * time.Minute
+	if value := os.Getenv("IDEMPOTENCY_TTL"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			log.Printf("Ignoring invalid IDEMPOTENCY_TTL %q, using %s", value, ttl)
+		} else {
+			ttl = parsed
+		}
+	}
+
+	return &idempotencyStore{
+		ttl:     ttl,
+		entries: make(map[string]*idempotentResponse),
+	}
+}
+
+// reserve returns the stored response for key, or marks key as pending and returns nil
+func (s *idempotencyStore) reserve(key string) *idempotentResponse {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for k, entry := range s.entries {
+		if !entry.pending && now.After(entry.expires) {
+			delete(s.entries, k)
+		}
+	}
+
+	if entry, ok := s.entries[key]; ok {
+		return entry
+	}
+	s.entries[key] = &idempotentResponse{pending: true}
+	return nil
+}
+
+// complete stores a successful response, or releases the key so a failed request can be retried
+func (s *idempotencyStore) complete(key string, recorder *responseRecorder) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if recorder.status < 200 || recorder.status >= 300 {
+		delete(s.entries, key)
+		return
+	}
+
+	s.entries[key] = &idempotentResponse{
+		status:  recorder.status,
+		header:  recorder.Header().Clone(),
+		body:    recorder.body.Bytes(),
+		expires: time.Now().Add(s.ttl),
+	}
+}
+
+// idempotent replays the original response when a request repeats an Idempotency-Key
+// instead of submitting the transaction again
+func (h *ApiHandler) idempotent(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(idempotencyHeader)
+		if key == "" {
+			next(w, r)
+			return
+		}
+
+		// Keys are scoped to the caller so different clients can't collide
+		scopedKey := r.Header.Get(apiKeyHeader) + "|" + h.requestOrg(r) + "|" + key
+
+		if stored := h.Idempotency.reserve(scopedKey); stored != nil {
+			if stored.pending {
+				writeError(w, http.StatusConflict, codeRequestInProgress, "A request with this "+idempotencyHeader+" is already in progress")
+				return
+			}
+			log.Printf("Replaying stored response for %s %s", idempotencyHeader, key)
+			for name, values := range stored.header {
+				w.Header()[name] = values
+			}
+			w.Header().Set("Idempotent-Replayed", "true")
+			w.WriteHeader(stored.status)
+			w.Write(stored.body)
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		next(recorder, r)
+		h.Idempotency.complete(scopedKey, recorder)
+	}
+}
+
+// responseRecorder passes a response through while keeping a copy of its status and body
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	rec.status = status
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	rec.body.Write(b)
+	return rec.ResponseWriter.Write(b)
+}
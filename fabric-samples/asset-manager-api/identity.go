@@ -0,0 +1,125 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+	"google.golang.org/grpc"
+)
+
+// gatewayDrainPeriod is how long a replaced gateway stays open so requests that
+// already picked it up can finish. It covers the longest gateway timeout.
+const gatewayDrainPeriod = 2 * time.Minute
+
+// orgConnection is what's needed to rebuild an org's gateway with a new identity
+type orgConnection struct {
+	config orgConfig
+	conn   *grpc.ClientConn
+}
+
+// gateway returns the org's current gateway, or nil if the org isn't enabled
+func (h *ApiHandler) gateway(org string) *client.Gateway {
+	h.gatewaysMu.RLock()
+	defer h.gatewaysMu.RUnlock()
+	return h.Gateways[org]
+}
+
+// contractFor returns a contract on the org's current gateway
+func (h *ApiHandler) contractFor(org string, channel string, chaincode string) *client.Contract {
+	// Hold the lock so a reload can't clear the cache between the two lookups
+	h.gatewaysMu.RLock()
+	defer h.gatewaysMu.RUnlock()
+	return h.contracts.get(h.Gateways[org], org, channel, chaincode)
+}
+
+// ReloadIdentityHandler handles POST /api/admin/reload-identity
+// It re-reads every org's certificate and private key from disk, and its admin
+// identity's, and swaps in new gateways, so rotated certificates are picked up
+// without a restart. Nothing is swapped unless every org's identity loads.
+func (h *ApiHandler) ReloadIdentityHandler(w http.ResponseWriter, r *http.Request) {
+	orgs := make([]string, 0, len(h.orgConns))
+	for org := range h.orgConns {
+		orgs = append(orgs, org)
+	}
+	sort.Strings(orgs)
+
+	gateways := make(map[string]*client.Gateway, len(orgs))
+	adminGateways := make(map[string]*client.Gateway, len(orgs))
+	closeLoaded := func() {
+		for _, loaded := range gateways {
+			loaded.Close()
+		}
+		for _, loaded := range adminGateways {
+			loaded.Close()
+		}
+	}
+	for _, org := range orgs {
+		gw, err := reloadGateway(h.orgConns[org])
+		if err != nil {
+			closeLoaded()
+			writeError(w, http.StatusInternalServerError, codeInternalError, fmt.Sprintf("Failed to reload identity for %s: %s", org, err))
+			return
+		}
+		gateways[org] = gw
+
+		adminGateway, err := connectAdminGateway(h.orgConns[org])
+		if err != nil {
+			closeLoaded()
+			writeError(w, http.StatusInternalServerError, codeInternalError, fmt.Sprintf("Failed to reload admin identity for %s: %s", org, err))
+			return
+		}
+		if adminGateway != nil {
+			adminGateways[org] = adminGateway
+		}
+	}
+
+	h.gatewaysMu.Lock()
+	previous := h.Gateways
+	previousAdmin := h.AdminGateways
+	h.Gateways = gateways
+	h.AdminGateways = adminGateways
+	h.contracts.clear()
+	h.gatewaysMu.Unlock()
+
+	// Requests that already hold the old gateways may still be waiting on them
+	time.AfterFunc(gatewayDrainPeriod, func() {
+		for _, gw := range previous {
+			gw.Close()
+		}
+		for _, gw := range previousAdmin {
+			gw.Close()
+		}
+	})
+
+	log.Printf("Reloaded identities for %v", orgs)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"message": "Identities reloaded", "orgs": orgs})
+}
+
+// reloadGateway builds a gateway on the org's existing connection. The connection
+// helpers panic on bad crypto material, which must not take down a running server.
+func reloadGateway(org orgConnection) (gw *client.Gateway, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("%v", p)
+		}
+	}()
+	return newGateway(org.conn, org.config), nil
+}
+
+// closeGateways closes the current gateways on shutdown
+func (h *ApiHandler) closeGateways() {
+	h.gatewaysMu.Lock()
+	defer h.gatewaysMu.Unlock()
+	for _, gw := range h.Gateways {
+		gw.Close()
+	}
+	for _, gw := range h.AdminGateways {
+		gw.Close()
+	}
+}
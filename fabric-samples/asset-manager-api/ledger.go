@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/hyperledger/fabric-protos-go-apiv2/common"
+	"google.golang.org/protobuf/proto"
+)
+
+// ledgerInfo is the progress of the request's channel ledger on the peer
+type ledgerInfo struct {
+	Channel           string `json:"channel"`
+	Height            uint64 `json:"height"`
+	CurrentBlockHash  string `json:"currentBlockHash"`
+	PreviousBlockHash string `json:"previousBlockHash"`
+}
+
+// GetLedgerInfoHandler handles GET /api/ledger/info
+// It evaluates qscc GetChainInfo and returns the block height and the hex hashes
+// of the latest block and the one before it
+func (h *ApiHandler) GetLedgerInfoHandler(w http.ResponseWriter, r *http.Request) {
+	channel := requestChannel(r)
+	info, err := h.chainInfo(r)
+	if err != nil {
+		writeFabricError(w, "Failed to evaluate transaction", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ledgerInfo{
+		Channel:           channel,
+		Height:            info.GetHeight(),
+		CurrentBlockHash:  hex.EncodeToString(info.GetCurrentBlockHash()),
+		PreviousBlockHash: hex.EncodeToString(info.GetPreviousBlockHash()),
+	})
+}
+
+// chainInfo evaluates qscc GetChainInfo for the request's channel
+func (h *ApiHandler) chainInfo(r *http.Request) (*common.BlockchainInfo, error) {
+	org := h.requestOrg(r)
+	channel := requestChannel(r)
+	qscc := h.contractFor(org, channel, qsccName)
+
+	log.Printf("--> Evaluating Transaction: GetChainInfo, Channel: %s", channel)
+	result, err := qscc.EvaluateTransaction("GetChainInfo", channel)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("<-- Transaction Evaluated: GetChainInfo, Channel: %s", channel)
+
+	info := &common.BlockchainInfo{}
+	if err := proto.Unmarshal(result, info); err != nil {
+		return nil, fmt.Errorf("failed to parse chain info: %w", err)
+	}
+	return info, nil
+}
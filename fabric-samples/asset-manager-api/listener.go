@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+)
+
+// eventListenerRetryDelay is how long the listener waits before subscribing
+// again after a channel's event stream ends
+const eventListenerRetryDelay = 5 * time.Second
+
+// eventCheckpoint records the last chaincode event the listener processed on a
+// channel, so a new subscription resumes right after it
+type eventCheckpoint interface {
+	client.Checkpoint
+	CheckpointChaincodeEvent(event *client.ChaincodeEvent) error
+	Close() error
+}
+
+// memoryCheckpoint keeps the position in memory only. It lets the listener
+// resubscribe without gaps, but not survive a restart.
+type memoryCheckpoint struct {
+	client.InMemoryCheckpointer
+}
+
+func (c *memoryCheckpoint) CheckpointChaincodeEvent(event *client.ChaincodeEvent) error {
+	c.InMemoryCheckpointer.CheckpointChaincodeEvent(event)
+	return nil
+}
+
+func (c *memoryCheckpoint) Close() error {
+	return nil
+}
+
+// eventListener follows the chaincode events of every allowed channel in the
+// background and drops cached reads of the assets they name, so writes made by
+// other API instances aren't served stale. It is off by default.
+type eventListener struct {
+	// checkpointDir holds a checkpoint file per channel, positions are only kept
+	// in memory when it is empty
+	checkpointDir string
+	// running tracks the listenChannel goroutines, see wait
+	running sync.WaitGroup
+}
+
+// newEventListener reads EVENT_LISTENER and EVENT_CHECKPOINT_DIR. It returns nil,
+// disabling the listener, unless EVENT_LISTENER is true. Without a checkpoint
+// directory a restarted listener starts again from the latest block.
+func newEventListener() (*eventListener, error) {
+	enabled, _ := strconv.ParseBool(os.Getenv("EVENT_LISTENER"))
+	if !enabled {
+		return nil, nil
+	}
+
+	listener := &eventListener{checkpointDir: os.Getenv("EVENT_CHECKPOINT_DIR")}
+	if listener.checkpointDir != "" {
+		if err := os.MkdirAll(listener.checkpointDir, 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create EVENT_CHECKPOINT_DIR: %w", err)
+		}
+	}
+	log.Printf("Event listener enabled, checkpoints in %q", listener.checkpointDir)
+	return listener, nil
+}
+
+// checkpoint opens the channel's checkpoint, <EVENT_CHECKPOINT_DIR>/<channel>-events.json
+func (l *eventListener) checkpoint(channel string) (eventCheckpoint, error) {
+	if l.checkpointDir == "" {
+		return &memoryCheckpoint{}, nil
+	}
+	return client.NewFileCheckpointer(filepath.Join(l.checkpointDir, channel+"-events.json"))
+}
+
+// wait blocks until every channel listener has stopped, reporting false when ctx
+// ends first
+func (l *eventListener) wait(ctx context.Context) bool {
+	done := make(chan struct{})
+	go func() {
+		l.running.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// startEventListener starts following every allowed channel when the listener is
+// enabled. The listeners stop, saving their checkpoints, once ctx is canceled.
+func (h *ApiHandler) startEventListener(ctx context.Context) {
+	if h.Events == nil {
+		return
+	}
+	for channel := range h.Channels {
+		checkpoint, err := h.Events.checkpoint(channel)
+		if err != nil {
+			fatalf("Failed to open the event checkpoint for channel %s: %v", channel, err)
+		}
+		h.Events.running.Add(1)
+		go func() {
+			defer h.Events.running.Done()
+			h.listenChannel(ctx, channel, checkpoint)
+		}()
+	}
+}
+
+// listenChannel processes the channel's chaincode events until ctx is done,
+// subscribing again from the checkpoint whenever the stream ends
+func (h *ApiHandler) listenChannel(ctx context.Context, channel string, checkpoint eventCheckpoint) {
+	defer checkpoint.Close()
+
+	for ctx.Err() == nil {
+		if err := h.followChannelEvents(ctx, channel, checkpoint); err != nil {
+			log.Printf("Event listener on channel %s: %v, retrying in %s", channel, err, eventListenerRetryDelay)
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(eventListenerRetryDelay):
+		}
+	}
+}
+
+// followChannelEvents reads one event subscription until it ends. Each event is
+// checkpointed once it has been processed.
+func (h *ApiHandler) followChannelEvents(ctx context.Context, channel string, checkpoint eventCheckpoint) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	network := h.gateway(h.DefaultOrg).GetNetwork(channel)
+	events, err := network.ChaincodeEvents(ctx, h.Channels[channel], client.WithCheckpoint(checkpoint))
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to chaincode events: %w", err)
+	}
+	log.Printf("Event listener subscribed to channel %s from block %d", channel, checkpoint.BlockNumber())
+
+	for event := range events {
+		h.processEvent(channel, event)
+		if err := checkpoint.CheckpointChaincodeEvent(event); err != nil {
+			log.Printf("Event listener on channel %s failed to save its checkpoint: %v", channel, err)
+		}
+	}
+	return fmt.Errorf("the event stream ended")
+}
+
+// processEvent drops the cached reads of the assets an event names
+func (h *ApiHandler) processEvent(channel string, event *client.ChaincodeEvent) {
+	if event.EventName != assetEventName && event.EventName != transferEventName {
+		return
+	}
+
+	dealerIDs := eventDealerIDs(event)
+	keys := make([]string, 0, len(dealerIDs))
+	for _, dealerID := range dealerIDs {
+		keys = append(keys, channelCacheKey(channel, dealerID))
+	}
+	h.ReadCache.invalidate(keys...)
+}
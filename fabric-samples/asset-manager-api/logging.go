@@ -0,0 +1,29 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+)
+
+// setupLogging routes all API logging through a slog handler filtered by
+// LOG_LEVEL (debug, info, warn or error, default info).
+// Existing log.Printf calls are emitted at info level, so LOG_LEVEL=warn
+// quiets the per-transaction logs.
+func setupLogging() {
+	level := slog.LevelInfo
+	if value := os.Getenv("LOG_LEVEL"); value != "" {
+		if err := level.UnmarshalText([]byte(value)); err != nil {
+			level = slog.LevelInfo
+			fmt.Fprintf(os.Stderr, "Ignoring invalid LOG_LEVEL %q, using info\n", value)
+		}
+	}
+
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level})))
+}
+
+// fatalf logs at error level, which is never filtered out, and exits
+func fatalf(format string, args ...any) {
+	slog.Error(fmt.Sprintf(format, args...))
+	os.Exit(1)
+}
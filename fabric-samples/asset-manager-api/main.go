@@ -1,74 +1,246 @@
 package main
 
 import (
-	"crypto/x509"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
 	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/hyperledger/fabric-gateway/pkg/client"
 	"github.com/hyperledger/fabric-gateway/pkg/identity"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials"
 )
 
 // Configuration for our API
-// These paths are relative to the 'test-network' directory
+// Per-organization identity and peer settings live in orgs.go
 const (
-	mspID         = "Org1MSP"
-	cryptoPath    = "organizations/peerOrganizations/org1.example.com"
-	certPath      = cryptoPath + "/users/User1@org1.example.com/msp/signcerts/User1@org1.example.com-cert.pem"
-	keyPath       = cryptoPath + "/users/User1@org1.example.com/msp/keystore/" // Will find the first key
-	tlsCertPath   = cryptoPath + "/peers/peer0.org1.example.com/tls/ca.crt"
-	peerEndpoint  = "peer0.org1.example.com:7051"
-	gatewayPeer   = "peer0.org1.example.com"
-	channelName   = "mychannel"
-	chaincodeName = "asset-manager"
+	testNetworkPath = "../test-network/"
+	channelName     = "mychannel"     // Default channel, more can be allowed with FABRIC_CHANNELS
+	chaincodeName   = "asset-manager" // Default chaincode on each channel
 )
 
 // Main function: sets up the API server
 func main() {
+	setupLogging()
 	log.Println("Starting Asset Manager API server...")
 
-	// Set up the gRPC connection to the Fabric peer
-	clientConnection := newGrpcConnection()
-	defer clientConnection.Close()
+	orgs, err := enabledOrgs()
+	if err != nil {
+		fatalf("Invalid organization configuration: %v", err)
+	}
+	if insecureMode() {
+		log.Println("WARNING: INSECURE=true, connecting to the peers WITHOUT TLS. Only use this against a local test network.")
+	}
+	if err := validateCryptoMaterial(orgs); err != nil {
+		fatalf("Cannot connect to Fabric, %v", err)
+	}
+
+	apiKeys, err := loadAPIKeys()
+	if err != nil {
+		fatalf("Invalid API key configuration: %v", err)
+	}
+
+	channels, err := loadChannels()
+	if err != nil {
+		fatalf("Invalid channel configuration: %v", err)
+	}
+
+	endorsingOrgs, err := loadEndorsingOrgs()
+	if err != nil {
+		fatalf("Invalid endorsing organization configuration: %v", err)
+	}
+
+	privateDataOrgs, err := loadPrivateDataOrgs()
+	if err != nil {
+		fatalf("Invalid private data organization configuration: %v", err)
+	}
 
-	// Create the Fabric Gateway client
-	gw := newGateway(clientConnection)
-	defer gw.Close()
+	auditLog, err := newAuditLog()
+	if err != nil {
+		fatalf("Invalid audit log configuration: %v", err)
+	}
 
-	// Get the network (channel)
-	network := gw.GetNetwork(channelName)
+	eventListener, err := newEventListener()
+	if err != nil {
+		fatalf("Invalid event listener configuration: %v", err)
+	}
 
-	// Create an 'ApiHandler' struct that holds our contract object
+	// Create an 'ApiHandler' struct that holds a gateway per org
 	apiHandler := &ApiHandler{
-		Contract: network.GetContract(chaincodeName),
+		Gateways:      make(map[string]*client.Gateway),
+		AdminGateways: make(map[string]*client.Gateway),
+		orgConns:      make(map[string]orgConnection),
+		DefaultOrg:    defaultOrgID(),
+		Channels:      channels,
+		contracts:     newContractCache(),
+		APIKeys:       apiKeys,
+		MaskedFields:  loadMaskedFields(),
+		Idempotency:   newIdempotencyStore(),
+		ReadCache:     newReadCache(),
+		ReadOnly:      newReadOnlyMode(),
+		EndorsingOrgs: endorsingOrgs,
+		Transactions:  newTransactionStore(),
+		PINAttempts:   newPINAttempts(),
+		WebhookSecret: loadWebhookSecret(),
+
+		RawTransactions: loadRawTransactions(),
+		PrivateDataOrgs: privateDataOrgs,
+		Audit:           auditLog,
+		Metrics:         newAPIMetrics(),
+		Events:          eventListener,
+	}
+
+	apiHandler.logEffectiveConfig(orgs)
+
+	for _, org := range orgs {
+		// Set up the gRPC connection to the org's Fabric peer
+		clientConnection := newGrpcConnection(org)
+		defer clientConnection.Close()
+
+		// Create the Fabric Gateway client for the org's identity
+		gw := newGateway(clientConnection, org)
+
+		apiHandler.Gateways[org.MSPID] = gw
+		apiHandler.orgConns[org.MSPID] = orgConnection{config: org, conn: clientConnection}
+		log.Printf("Connected gateway for %s via %s", org.MSPID, peerEndpoints(org))
+
+		// Admin routes submit with the org's admin identity when it has one
+		adminGateway, err := connectAdminGateway(apiHandler.orgConns[org.MSPID])
+		if err != nil {
+			fatalf("Invalid admin identity configuration: %v", err)
+		}
+		if adminGateway != nil {
+			apiHandler.AdminGateways[org.MSPID] = adminGateway
+		}
+	}
+	defer apiHandler.closeGateways()
+
+	if _, ok := apiHandler.Gateways[apiHandler.DefaultOrg]; !ok {
+		fatalf("Default organization %s is not in the enabled organizations", apiHandler.DefaultOrg)
+	}
+	if err := apiHandler.checkChaincodes(); err != nil {
+		fatalf("Startup check failed: %v", err)
 	}
 
+	// SIGINT and SIGTERM start a graceful shutdown, which also stops the event listener
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	apiHandler.startEventListener(ctx)
+
 	// Set up the web server routes
 	r := mux.NewRouter()
-	r.HandleFunc("/api/assets", apiHandler.CreateAssetHandler).Methods("POST")
-	r.HandleFunc("/api/assets/{id}", apiHandler.ReadAssetHandler).Methods("GET")
-	r.HandleFunc("/api/assets/history/{id}", apiHandler.GetAssetHistoryHandler).Methods("GET")
-	r.HandleFunc("/api/assets/{id}", apiHandler.UpdateAssetHandler).Methods("PUT")
-	r.HandleFunc("/api/assets/{id}", apiHandler.DeleteAssetHandler).Methods("DELETE")
-	r.HandleFunc("/api/assets", apiHandler.GetAllAssetsHandler).Methods("GET")
-	// Add more routes here for Update, History, etc.
+	r.Use(apiHandler.inFlightMiddleware)
+	r.Use(apiHandler.authMiddleware)
+	r.Use(apiHandler.orgMiddleware)
+	r.Use(apiHandler.endorsingOrgsMiddleware)
+	r.Use(asyncMiddleware)
+	apiHandler.registerProbes(r)
+
+	// Behind a shared ingress every route lives under API_BASE_PATH,
+	// the probes stay at the root where the kubelet calls them
+	api := r
+	if basePath := apiBasePath(); basePath != "" {
+		api = r.PathPrefix(basePath).Subrouter()
+		log.Printf("Serving routes under base path %s", basePath)
+	}
+	apiHandler.registerRoutes(api)
+
+	// The same routes are served per channel under /channels/{channel}
+	channelRouter := api.PathPrefix("/channels/{channel}").Subrouter()
+	channelRouter.Use(apiHandler.channelMiddleware)
+	apiHandler.registerRoutes(channelRouter)
+
+	var handler http.Handler = r
+	if minBytes, enabled := loadCompression(); enabled {
+		handler = compressionMiddleware(handler, minBytes)
+	}
+	// Recovery sits outside compression, which drops a panicking handler's
+	// buffered body so the 500 reaches the client, and inside the access log so
+	// the 500 is logged
+	if enabled, stackTraces := loadPanicRecovery(); enabled {
+		handler = recoveryMiddleware(handler, stackTraces)
+	}
 
 	log.Println("Server is listening on http://localhost:8080")
 	// Start the server
-	log.Fatal(http.ListenAndServe(":8080", r))
+	// The access log wraps the whole router so unmatched routes are logged too,
+	// and outside compression so it records the bytes actually sent
+	server := &http.Server{Addr: ":8080", Handler: accessLogMiddleware(handler)}
+	if err := apiHandler.serve(ctx, server); err != nil {
+		fatalf("Server stopped: %v", err)
+	}
 }
 
-// ApiHandler holds the contract object
+// ApiHandler holds a gateway for each enabled organization
+// along with the settings shared by all handlers
 type ApiHandler struct {
-	Contract *client.Contract
+	// Gateways is replaced by ReloadIdentityHandler, read it through gateway or contractFor
+	Gateways   map[string]*client.Gateway
+	gatewaysMu sync.RWMutex
+	orgConns   map[string]orgConnection
+	DefaultOrg string
+	// Channels maps each allowed channel to the chaincode deployed on it
+	Channels  map[string]string
+	contracts *contractCache
+
+	// AdminGateways submit admin routes with each org's admin identity, orgs
+	// without one are absent. Replaced with Gateways, read it through adminContractFor.
+	AdminGateways map[string]*client.Gateway
+
+	// APIKeys maps API keys to caller roles, authentication is off when empty
+	APIKeys map[string]string
+	// MaskedFields are the asset fields hidden from restricted callers
+	MaskedFields map[string]bool
+	// Idempotency remembers responses to recent Idempotency-Key requests
+	Idempotency *idempotencyStore
+	// ReadCache optionally caches ReadAsset results, nil when disabled
+	ReadCache *readCache
+	// ReadOnly rejects submits while it is enabled
+	ReadOnly *readOnlyMode
+	// EndorsingOrgs are the orgs clients may target with X-Endorsing-Orgs
+	EndorsingOrgs map[string]bool
+	// Transactions remembers async submits for the transaction status endpoint
+	Transactions *transactionStore
+	// PINAttempts rate-limits PIN verification per dealer
+	PINAttempts *pinAttempts
+	// WebhookSecret signs POST /api/webhook bodies, the endpoint is off when empty
+	WebhookSecret []byte
+	// RawTransactions are the chaincode functions POST /api/tx may call, the endpoint is off when empty
+	RawTransactions map[string]bool
+	// PrivateDataOrgs are the orgs whose identities may read private asset details
+	PrivateDataOrgs map[string]bool
+	// Audit appends a JSON line per submit to AUDIT_LOG_FILE, nil when disabled
+	Audit *auditLog
+	// Metrics are served on /metrics
+	Metrics *apiMetrics
+	// Events follows chaincode events in the background, nil when disabled
+	Events *eventListener
+}
+
+// contract returns the contract for the organization and channel selected by the request.
+// orgMiddleware and channelMiddleware have already rejected unknown values.
+func (h *ApiHandler) contract(r *http.Request) *client.Contract {
+	org := h.requestOrg(r)
+	channel := requestChannel(r)
+	if isAdminRequest(r) {
+		if contract := h.adminContractFor(org, channel, h.Channels[channel]); contract != nil {
+			return contract
+		}
+	}
+	return h.contractFor(org, channel, h.Channels[channel])
 }
 
 // CreateAssetHandler handles POST /api/assets
@@ -85,28 +257,60 @@ func (h *ApiHandler) CreateAssetHandler(w http.ResponseWriter, r *http.Request)
 		TRANSAMOUNT string `json:"TRANSAMOUNT"` // Receive as string
 		TRANSTYPE   string `json:"TRANSTYPE"`
 		REMARKS     string `json:"REMARKS"`
+		Currency    string `json:"Currency"`
+
+		Metadata map[string]string `json:"Metadata"`
 	}
 
 	// Decode the JSON request body into our struct
-	if err := json.NewDecoder(r.Body).Decode(&asset); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	if err := decodeAssetBody(r, opCreateAsset, &asset); err != nil {
+		writeAssetBodyError(w, err)
+		return
+	}
+	asset.DEALERID = normalizeDealerID(asset.DEALERID)
+	if !requireDealerID(w, asset.DEALERID) {
 		return
 	}
 
-	// Call the 'CreateAsset' function in our smart contract
-	log.Printf("--> Submitting Transaction: CreateAsset, ID: %s", asset.DEALERID)
-	_, err := h.Contract.SubmitTransaction("CreateAsset",
+	// Validate the numeric fields here so clients get a clear error
+	// instead of a conversion failure from deep inside the chaincode
+	balance, err := parseAmount("BALANCE", asset.BALANCE)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, err.Error())
+		return
+	}
+	transAmount, err := parseAmount("TRANSAMOUNT", asset.TRANSAMOUNT)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, err.Error())
+		return
+	}
+
+	// The MPIN goes in the transient map so it never becomes a transaction argument
+	transient := mpinTransient(asset.MPIN)
+	args := []string{
 		asset.DEALERID,
 		asset.MSISDN,
-		asset.MPIN,
-		asset.BALANCE,
+		balance,
 		asset.STATUS,
-		asset.TRANSAMOUNT,
+		transAmount,
 		asset.TRANSTYPE,
 		asset.REMARKS,
-	)
+		metadataArg(asset.Metadata),
+		asset.Currency,
+	}
+
+	// A dry run validates the request without committing anything
+	if isSimulation(r) {
+		h.simulateWithTransient(w, r, "CreateAsset", asset.DEALERID, transient, args...)
+		return
+	}
+
+	// Call the 'CreateAsset' function in our smart contract
+	log.Printf("--> Submitting Transaction: CreateAsset, ID: %s", asset.DEALERID)
+	_, err = h.submitWithTransient(r, "CreateAsset", transient, args...)
+	h.invalidateReads(r, asset.DEALERID)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to submit transaction: %s", err), http.StatusInternalServerError)
+		writeFabricError(w, "Failed to submit transaction", err)
 		return
 	}
 
@@ -117,50 +321,279 @@ func (h *ApiHandler) CreateAssetHandler(w http.ResponseWriter, r *http.Request)
 }
 
 // ReadAssetHandler handles GET /api/assets/{id}
-// It reads the asset ID from the URL path
+// It reads the asset ID from the URL path. ?nullOnMissing=true answers a missing
+// asset with 200 and a null body instead of 404. The response carries an ETag and a
+// matching If-None-Match gets 304 Not Modified.
 func (h *ApiHandler) ReadAssetHandler(w http.ResponseWriter, r *http.Request) {
 	// Get the 'id' variable from the URL
 	vars := mux.Vars(r)
-	assetID := vars["id"]
+	assetID := normalizeDealerID(vars["id"])
+	if !requireDealerID(w, assetID) {
+		return
+	}
+
+	var result []byte
+	if wantsCommittedRead(r) {
+		// ?consistency=committed checks every org's peers agree instead of trusting one peer
+		log.Printf("--> Evaluating Transaction (committed): ReadAsset, ID: %s", assetID)
+		var err error
+		result, err = h.evaluateCommitted(r, "ReadAsset", assetID)
+		if errors.Is(err, errPeersDisagree) {
+			writeError(w, http.StatusServiceUnavailable, codeInconsistentRead, err.Error())
+			return
+		}
+		if err != nil {
+			writeReadAssetError(w, r, err)
+			return
+		}
+		log.Printf("<-- Transaction Evaluated (committed): ReadAsset, ID: %s", assetID)
+	} else {
+		// Serve hot keys from the read cache when it is enabled
+		cacheKey := readCacheKey(r, assetID)
+		var cached bool
+		result, cached = h.ReadCache.get(cacheKey)
+		if !cached {
+			// Call the 'ReadAsset' function in our smart contract
+			log.Printf("--> Evaluating Transaction: ReadAsset, ID: %s", assetID)
+			var err error
+			result, err = h.contract(r).EvaluateTransaction("ReadAsset", assetID)
+			if err != nil {
+				writeReadAssetError(w, r, err)
+				return
+			}
+			log.Printf("<-- Transaction Evaluated: ReadAsset, ID: %s", assetID)
+			h.ReadCache.put(cacheKey, result)
+		}
+	}
+
+	// ?includeMeta=true wraps the asset with the transaction and block of its last write
+	if includeMeta, err := strconv.ParseBool(r.URL.Query().Get("includeMeta")); err == nil && includeMeta {
+		h.writeAssetWithMeta(w, r, assetID, result)
+		return
+	}
+
+	// Send the result back as JSON, hiding sensitive fields the caller may not see.
+	// The ETag lets polling clients revalidate with If-None-Match.
+	h.writeAssetWithETag(w, r, result)
+}
+
+// writeReadAssetError writes the error of a failed ReadAsset. With ?nullOnMissing=true
+// a missing asset is a 200 with a null body rather than a 404.
+func writeReadAssetError(w http.ResponseWriter, r *http.Request, err error) {
+	if nullOnMissing, parseErr := strconv.ParseBool(r.URL.Query().Get("nullOnMissing")); parseErr == nil && nullOnMissing && isNotFoundError(err) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte("null\n"))
+		return
+	}
+	writeFabricError(w, "Failed to evaluate transaction", err)
+}
+
+// GetArchivedAssetHandler handles GET /api/assets/{id}/archived
+// It returns the final state of an asset deleted with ?archive=true
+func (h *ApiHandler) GetArchivedAssetHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	assetID := normalizeDealerID(vars["id"])
+	if !requireDealerID(w, assetID) {
+		return
+	}
 
-	// Call the 'ReadAsset' function in our smart contract
-	log.Printf("--> Evaluating Transaction: ReadAsset, ID: %s", assetID)
-	result, err := h.Contract.EvaluateTransaction("ReadAsset", assetID)
+	log.Printf("--> Evaluating Transaction: GetArchivedAsset, ID: %s", assetID)
+	result, err := h.contract(r).EvaluateTransaction("GetArchivedAsset", assetID)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to evaluate transaction: %s", err), http.StatusInternalServerError)
+		writeFabricError(w, "Failed to evaluate transaction", err)
+		return
+	}
+	log.Printf("<-- Transaction Evaluated: GetArchivedAsset, ID: %s", assetID)
+
+	h.writeAsset(w, r, result)
+}
+
+// HeadAssetHandler handles HEAD /api/assets/{id}
+// It answers 200 if the asset exists and 404 if not, without a body
+func (h *ApiHandler) HeadAssetHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	assetID := normalizeDealerID(vars["id"])
+	if strings.TrimSpace(assetID) == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	exists, err := h.assetExists(r, assetID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if !exists {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// AssetExistsHandler handles GET /api/assets/{id}/exists
+// It returns {"exists":true|false} without transferring the asset itself
+func (h *ApiHandler) AssetExistsHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	assetID := normalizeDealerID(vars["id"])
+	if !requireDealerID(w, assetID) {
+		return
+	}
+
+	exists, err := h.assetExists(r, assetID)
+	if err != nil {
+		writeFabricError(w, "Failed to evaluate transaction", err)
 		return
 	}
-	log.Printf("<-- Transaction Evaluated: ReadAsset, ID: %s", assetID)
 
-	// Send the result back as JSON
 	w.Header().Set("Content-Type", "application/json")
-	// The result from the chaincode is raw JSON, so we can write it directly
-	w.Write(result)
+	json.NewEncoder(w).Encode(map[string]bool{"exists": exists})
+}
+
+// assetExists evaluates the chaincode's AssetExists function
+func (h *ApiHandler) assetExists(r *http.Request, assetID string) (bool, error) {
+	log.Printf("--> Evaluating Transaction: AssetExists, ID: %s", assetID)
+	result, err := h.contract(r).EvaluateTransaction("AssetExists", assetID)
+	if err != nil {
+		return false, err
+	}
+	log.Printf("<-- Transaction Evaluated: AssetExists, ID: %s", assetID)
+
+	return strconv.ParseBool(string(result))
 }
 
 // GetAssetHistoryHandler handles GET /api/assets/history/{id}
 func (h *ApiHandler) GetAssetHistoryHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
-	assetID := vars["id"]
+	assetID := normalizeDealerID(vars["id"])
+	if !requireDealerID(w, assetID) {
+		return
+	}
+
+	// Page through the history when the client asks for a limit or offset
+	query := r.URL.Query()
+	if query.Has("limit") || query.Has("offset") {
+		h.getAssetHistoryPage(w, r, assetID)
+		return
+	}
+
+	// ?diff=true adds the fields that changed to each record
+	diff, err := strconv.ParseBool(query.Get("diff"))
+	diff = err == nil && diff
+
+	log.Printf("--> Evaluating Transaction: GetBoundedAssetHistory, ID: %s, changes: %t", assetID, diff)
+	result, err := h.contract(r).EvaluateTransaction("GetBoundedAssetHistory", assetID, strconv.FormatBool(diff))
+	if err != nil {
+		writeFabricError(w, "Failed to evaluate transaction", err)
+		return
+	}
+	log.Printf("<-- Transaction Evaluated: GetBoundedAssetHistory, ID: %s", assetID)
+
+	// The chaincode stops reading at its configured maximum. The body stays a
+	// plain array of records, the truncation is reported in a header.
+	var history struct {
+		Records    json.RawMessage `json:"records"`
+		Truncated  bool            `json:"truncated"`
+		MaxRecords int             `json:"maxRecords"`
+	}
+	if err := json.Unmarshal(result, &history); err != nil {
+		writeError(w, http.StatusInternalServerError, codeInternalError, fmt.Sprintf("Failed to parse history: %s", err))
+		return
+	}
+	if history.Truncated {
+		w.Header().Set("History-Truncated", "true")
+		w.Header().Set("History-Max-Records", strconv.Itoa(history.MaxRecords))
+	}
+
+	h.writeHistory(w, r, history.Records)
+}
+
+// getAssetHistoryPage handles GET /api/assets/history/{id}?limit=&offset=
+func (h *ApiHandler) getAssetHistoryPage(w http.ResponseWriter, r *http.Request, assetID string) {
+	limit, err := queryInt(r, "limit", 100)
+	if err != nil || limit <= 0 {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "limit must be a positive integer")
+		return
+	}
+	offset, err := queryInt(r, "offset", 0)
+	if err != nil || offset < 0 {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "offset must be a non-negative integer")
+		return
+	}
+
+	log.Printf("--> Evaluating Transaction: GetAssetHistoryPage, ID: %s, limit: %d, offset: %d", assetID, limit, offset)
+	result, err := h.contract(r).EvaluateTransaction("GetAssetHistoryPage", assetID, strconv.Itoa(limit), strconv.Itoa(offset))
+	if err != nil {
+		writeFabricError(w, "Failed to evaluate transaction", err)
+		return
+	}
+	log.Printf("<-- Transaction Evaluated: GetAssetHistoryPage, ID: %s", assetID)
+
+	h.writeHistoryPage(w, r, result)
+}
+
+// GetLatestAssetTransactionHandler handles GET /api/assets/{id}/latest
+// It returns only the most recent history entry for the asset
+func (h *ApiHandler) GetLatestAssetTransactionHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	assetID := normalizeDealerID(vars["id"])
+	if !requireDealerID(w, assetID) {
+		return
+	}
 
-	log.Printf("--> Evaluating Transaction: GetAssetHistory, ID: %s", assetID)
-	result, err := h.Contract.EvaluateTransaction("GetAssetHistory", assetID)
+	log.Printf("--> Evaluating Transaction: GetLatestAssetTransaction, ID: %s", assetID)
+	result, err := h.contract(r).EvaluateTransaction("GetLatestAssetTransaction", assetID)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to evaluate transaction: %s", err), http.StatusInternalServerError)
+		writeFabricError(w, "Failed to evaluate transaction", err)
+		return
+	}
+	log.Printf("<-- Transaction Evaluated: GetLatestAssetTransaction, ID: %s", assetID)
+
+	// The chaincode returns an empty result when the key has no history
+	if len(result) == 0 {
+		writeError(w, http.StatusNotFound, codeAssetNotFound, fmt.Sprintf("No history found for asset %s", assetID))
 		return
 	}
-	log.Printf("<-- Transaction Evaluated: GetAssetHistory, ID: %s", assetID)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.Write(result)
 }
 
+// GetAssetAtTimeHandler handles GET /api/assets/{id}/at?time=
+// It returns the asset as it was at the given RFC3339 timestamp
+func (h *ApiHandler) GetAssetAtTimeHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	assetID := normalizeDealerID(vars["id"])
+	if !requireDealerID(w, assetID) {
+		return
+	}
+
+	at := r.URL.Query().Get("time")
+	if _, err := time.Parse(time.RFC3339, at); err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "time must be an RFC3339 timestamp")
+		return
+	}
+
+	log.Printf("--> Evaluating Transaction: GetAssetAtTime, ID: %s, Time: %s", assetID, at)
+	result, err := h.contract(r).EvaluateTransaction("GetAssetAtTime", assetID, at)
+	if err != nil {
+		writeFabricError(w, "Failed to evaluate transaction", err)
+		return
+	}
+	log.Printf("<-- Transaction Evaluated: GetAssetAtTime, ID: %s", assetID)
+
+	// Send the result back as JSON, hiding sensitive fields the caller may not see
+	h.writeAsset(w, r, result)
+}
+
 // UpdateAssetHandler handles PUT /api/assets/{id}
 // It updates an existing asset with new data
 func (h *ApiHandler) UpdateAssetHandler(w http.ResponseWriter, r *http.Request) {
 	// Get the 'id' variable from the URL
 	vars := mux.Vars(r)
-	assetID := vars["id"]
+	assetID := normalizeDealerID(vars["id"])
+	if !requireDealerID(w, assetID) {
+		return
+	}
 
 	// Define a temporary struct to capture the incoming JSON
 	var assetUpdate struct {
@@ -171,29 +604,44 @@ func (h *ApiHandler) UpdateAssetHandler(w http.ResponseWriter, r *http.Request)
 		TRANSAMOUNT string `json:"TRANSAMOUNT"`
 		TRANSTYPE   string `json:"TRANSTYPE"`
 		REMARKS     string `json:"REMARKS"`
+		Currency    string `json:"Currency"` // Kept as stored when absent
+
+		Metadata map[string]string `json:"Metadata"` // Kept as stored when absent
 	}
 
 	// Decode the JSON request body into our struct
-	if err := json.NewDecoder(r.Body).Decode(&assetUpdate); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	if err := decodeAssetBody(r, opUpdateAsset, &assetUpdate); err != nil {
+		writeAssetBodyError(w, err)
 		return
 	}
 
-	// Call the 'UpdateAsset' function in our smart contract
-	// Note: The smart contract must have an "UpdateAsset" function
-	log.Printf("--> Submitting Transaction: UpdateAsset, ID: %s", assetID)
-	_, err := h.Contract.SubmitTransaction("UpdateAsset",
+	// An empty MPIN keeps the stored one, a new one travels in the transient map
+	transient := mpinTransient(assetUpdate.MPIN)
+	args := []string{
 		assetID, // The ID from the URL
 		assetUpdate.MSISDN,
-		assetUpdate.MPIN,
 		assetUpdate.BALANCE,
 		assetUpdate.STATUS,
 		assetUpdate.TRANSAMOUNT,
 		assetUpdate.TRANSTYPE,
 		assetUpdate.REMARKS,
-	)
+		metadataArg(assetUpdate.Metadata),
+		assetUpdate.Currency,
+	}
+
+	// A dry run validates the request without committing anything
+	if isSimulation(r) {
+		h.simulateWithTransient(w, r, "UpdateAsset", assetID, transient, args...)
+		return
+	}
+
+	// Call the 'UpdateAsset' function in our smart contract
+	// Note: The smart contract must have an "UpdateAsset" function
+	log.Printf("--> Submitting Transaction: UpdateAsset, ID: %s", assetID)
+	_, err := h.submitWithTransient(r, "UpdateAsset", transient, args...)
+	h.invalidateReads(r, assetID)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to submit transaction: %s", err), http.StatusInternalServerError)
+		writeFabricError(w, "Failed to submit transaction", err)
 		return
 	}
 
@@ -203,86 +651,842 @@ func (h *ApiHandler) UpdateAssetHandler(w http.ResponseWriter, r *http.Request)
 	json.NewEncoder(w).Encode(map[string]string{"message": "Asset " + assetID + " updated successfully"})
 }
 
+// UpsertAssetHandler handles PUT /api/assets/{id}/upsert
+// It takes the same body as UpdateAssetHandler and has UpsertAsset create the asset
+// when it doesn't exist, answering 201, or update it when it does, answering 200.
+func (h *ApiHandler) UpsertAssetHandler(w http.ResponseWriter, r *http.Request) {
+	assetID := normalizeDealerID(mux.Vars(r)["id"])
+	if !requireDealerID(w, assetID) {
+		return
+	}
+
+	var asset struct {
+		MSISDN      string `json:"MSISDN"`
+		MPIN        string `json:"MPIN"`
+		BALANCE     string `json:"BALANCE"`
+		STATUS      string `json:"STATUS"`
+		TRANSAMOUNT string `json:"TRANSAMOUNT"`
+		TRANSTYPE   string `json:"TRANSTYPE"`
+		REMARKS     string `json:"REMARKS"`
+		Currency    string `json:"Currency"`
+
+		Metadata map[string]string `json:"Metadata"`
+	}
+	if err := decodeAssetBody(r, opUpdateAsset, &asset); err != nil {
+		writeAssetBodyError(w, err)
+		return
+	}
+
+	transient := mpinTransient(asset.MPIN)
+	args := []string{
+		assetID,
+		asset.MSISDN,
+		asset.BALANCE,
+		asset.STATUS,
+		asset.TRANSAMOUNT,
+		asset.TRANSTYPE,
+		asset.REMARKS,
+		metadataArg(asset.Metadata),
+		asset.Currency,
+	}
+
+	if isSimulation(r) {
+		h.simulateWithTransient(w, r, "UpsertAsset", assetID, transient, args...)
+		return
+	}
+
+	log.Printf("--> Submitting Transaction: UpsertAsset, ID: %s", assetID)
+	result, err := h.submitWithTransient(r, "UpsertAsset", transient, args...)
+	h.invalidateReads(r, assetID)
+	if err != nil {
+		writeFabricError(w, "Failed to submit transaction", err)
+		return
+	}
+
+	created, err := strconv.ParseBool(string(result))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, codeInternalError, fmt.Sprintf("Failed to parse upsert result: %s", err))
+		return
+	}
+	log.Printf("<-- Transaction Committed: UpsertAsset, ID: %s, Created: %t", assetID, created)
+
+	if created {
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Asset " + assetID + " created successfully"})
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Asset " + assetID + " updated successfully"})
+}
+
+// PatchAssetHandler handles PATCH /api/assets/{id} with a JSON merge patch (RFC 7386)
+// body. The chaincode applies the patch to the stored asset inside the transaction.
+func (h *ApiHandler) PatchAssetHandler(w http.ResponseWriter, r *http.Request) {
+	// Get the 'id' variable from the URL
+	vars := mux.Vars(r)
+	assetID := normalizeDealerID(vars["id"])
+	if !requireDealerID(w, assetID) {
+		return
+	}
+
+	// A merge patch must be an object; anything else would replace the whole asset
+	var patch map[string]json.RawMessage
+	if err := decodeJSONBody(r, &patch); err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, err.Error())
+		return
+	}
+	if patch == nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "the merge patch must be a JSON object")
+		return
+	}
+	// A new MPIN is moved out of the patch into the transient map
+	var transient map[string][]byte
+	if rawMPIN, ok := patch["MPIN"]; ok {
+		var mpin string
+		if err := json.Unmarshal(rawMPIN, &mpin); err != nil || mpin == "" {
+			writeError(w, http.StatusBadRequest, codeInvalidRequest, "MPIN must be a non-empty string")
+			return
+		}
+		delete(patch, "MPIN")
+		transient = mpinTransient(mpin)
+	}
+	patchJSON, err := json.Marshal(patch)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, codeInternalError, err.Error())
+		return
+	}
+
+	// A dry run validates the request without committing anything
+	if isSimulation(r) {
+		h.simulateWithTransient(w, r, "UpdateAssetFields", assetID, transient, assetID, string(patchJSON))
+		return
+	}
+
+	log.Printf("--> Submitting Transaction: UpdateAssetFields, ID: %s", assetID)
+	_, err = h.submitWithTransient(r, "UpdateAssetFields", transient, assetID, string(patchJSON))
+	h.invalidateReads(r, assetID)
+	if err != nil {
+		writeFabricError(w, "Failed to submit transaction", err)
+		return
+	}
+
+	log.Printf("<-- Transaction Committed: UpdateAssetFields, ID: %s", assetID)
+	// Send a success response
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Asset " + assetID + " updated successfully"})
+}
+
 // DeleteAssetHandler handles DELETE /api/assets/{id}
+// A missing asset is a 404 unless ?ifExists=true, which answers 204 instead.
 func (h *ApiHandler) DeleteAssetHandler(w http.ResponseWriter, r *http.Request) {
 	// Get the 'id' variable from the URL
 	vars := mux.Vars(r)
-	assetID := vars["id"]
+	assetID := normalizeDealerID(vars["id"])
+	if !requireDealerID(w, assetID) {
+		return
+	}
+
+	// ?archive=true keeps the final state readable at /api/assets/{id}/archived
+	name := "DeleteAsset"
+	archive, err := strconv.ParseBool(r.URL.Query().Get("archive"))
+	if err == nil && archive {
+		name = "DeleteAndArchiveAsset"
+	}
+	args := []string{assetID}
+
+	// ?expectedBalance= (or X-Expected-Balance) only deletes while the asset still
+	// holds that balance, so the wrong account isn't deleted by mistake
+	expectedBalance := r.URL.Query().Get("expectedBalance")
+	if expectedBalance == "" {
+		expectedBalance = r.Header.Get("X-Expected-Balance")
+	}
+	if expectedBalance != "" {
+		if archive {
+			writeError(w, http.StatusBadRequest, codeInvalidRequest, "expectedBalance cannot be combined with archive")
+			return
+		}
+		expected, err := parseAmount("expectedBalance", expectedBalance)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, codeInvalidRequest, err.Error())
+			return
+		}
+		name = "DeleteAssetIfBalance"
+		args = append(args, expected)
+	}
 
 	// Call the 'DeleteAsset' function in our smart contract
 	// Note: Your smart contract must have a "DeleteAsset" function
-	log.Printf("--> Submitting Transaction: DeleteAsset, ID: %s", assetID)
-	_, err := h.Contract.SubmitTransaction("DeleteAsset", assetID)
+	log.Printf("--> Submitting Transaction: %s, ID: %s", name, assetID)
+	_, err = h.submitTransaction(r, name, args...)
+	h.invalidateReads(r, assetID)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to submit transaction: %s", err), http.StatusInternalServerError)
+		// ?ifExists=true makes retries safe: the asset being gone already is success
+		if ifExists, _ := strconv.ParseBool(r.URL.Query().Get("ifExists")); ifExists && isNotFoundError(err) {
+			log.Printf("<-- Asset %s already absent, nothing to delete", assetID)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		writeFabricError(w, "Failed to submit transaction", err)
 		return
 	}
 
-	log.Printf("<-- Transaction Committed: DeleteAsset, ID: %s", assetID)
+	log.Printf("<-- Transaction Committed: %s, ID: %s", name, assetID)
 	// Send a success response
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]string{"message": "Asset " + assetID + " deleted successfully"})
 }
 
 // GetAllAssetsHandler handles GET /api/assets
+// Any of the lists can be ordered with ?sortBy=balance|status|dealerId|createdAt&order=asc|desc,
+// except the streamed one
 func (h *ApiHandler) GetAllAssetsHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	order, err := parseAssetSort(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, err.Error())
+		return
+	}
+
+	// Filter on creation time when either bound is given
+	if query.Has("createdFrom") || query.Has("createdTo") {
+		h.getAssetsCreatedBetween(w, r)
+		return
+	}
+	if query.Has("modifiedBy") {
+		h.getAssetsModifiedBy(w, r)
+		return
+	}
+	if query.Has("prefix") {
+		h.getAssetsByPrefix(w, r)
+		return
+	}
+	if stream, _ := strconv.ParseBool(query.Get("stream")); stream {
+		if order != nil {
+			writeError(w, http.StatusBadRequest, codeInvalidRequest, "a streamed list cannot be sorted")
+			return
+		}
+		h.streamAllAssets(w, r)
+		return
+	}
+
 	// Call the 'GetAllAssets' function in our smart contract
 	// Note: Your smart contract must have a "GetAllAssets" function
 	log.Printf("--> Evaluating Transaction: GetAllAssets")
-	result, err := h.Contract.EvaluateTransaction("GetAllAssets")
+	result, err := h.contract(r).EvaluateTransaction("GetAllAssets")
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to evaluate transaction: %s", err), http.StatusInternalServerError)
+		writeFabricError(w, "Failed to evaluate transaction", err)
 		return
 	}
 	log.Printf("<-- Transaction Evaluated: GetAllAssets")
 
-	// Send the result back as JSON
+	// Send the result back as JSON, hiding sensitive fields the caller may not see
+	h.writeAssetList(w, r, result)
+}
+
+// getAssetsCreatedBetween handles GET /api/assets?createdFrom=&createdTo=
+// Either bound may be omitted to leave that end of the range open
+func (h *ApiHandler) getAssetsCreatedBetween(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	createdFrom := query.Get("createdFrom")
+	if createdFrom == "" {
+		createdFrom = "0001-01-01T00:00:00Z"
+	}
+	createdTo := query.Get("createdTo")
+	if createdTo == "" {
+		createdTo = "9999-12-31T23:59:59Z"
+	}
+	for name, value := range map[string]string{"createdFrom": createdFrom, "createdTo": createdTo} {
+		if _, err := time.Parse(time.RFC3339, value); err != nil {
+			writeError(w, http.StatusBadRequest, codeInvalidRequest, fmt.Sprintf("%s must be an RFC3339 timestamp", name))
+			return
+		}
+	}
+
+	log.Printf("--> Evaluating Transaction: GetAssetsCreatedBetween, From: %s, To: %s", createdFrom, createdTo)
+	result, err := h.contract(r).EvaluateTransaction("GetAssetsCreatedBetween", createdFrom, createdTo)
+	if err != nil {
+		writeFabricError(w, "Failed to evaluate transaction", err)
+		return
+	}
+	log.Printf("<-- Transaction Evaluated: GetAssetsCreatedBetween")
+
+	h.writeAssetList(w, r, result)
+}
+
+// getAssetsModifiedBy handles GET /api/assets?modifiedBy=
+// The value is a client identity ID as recorded in LastModifiedBy
+func (h *ApiHandler) getAssetsModifiedBy(w http.ResponseWriter, r *http.Request) {
+	modifiedBy := r.URL.Query().Get("modifiedBy")
+	if modifiedBy == "" {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "modifiedBy must not be empty")
+		return
+	}
+
+	log.Printf("--> Evaluating Transaction: GetAssetsModifiedBy, Identity: %s", modifiedBy)
+	result, err := h.contract(r).EvaluateTransaction("GetAssetsModifiedBy", modifiedBy)
+	if err != nil {
+		writeFabricError(w, "Failed to evaluate transaction", err)
+		return
+	}
+	log.Printf("<-- Transaction Evaluated: GetAssetsModifiedBy")
+
+	h.writeAssetList(w, r, result)
+}
+
+// getAssetsByPrefix handles GET /api/assets?prefix=
+// The prefix must be non-empty so it never turns into a full scan.
+func (h *ApiHandler) getAssetsByPrefix(w http.ResponseWriter, r *http.Request) {
+	prefix := normalizeDealerID(r.URL.Query().Get("prefix"))
+	if prefix == "" {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "prefix must not be empty")
+		return
+	}
+
+	log.Printf("--> Evaluating Transaction: GetAssetsByPrefix, Prefix: %s", prefix)
+	result, err := h.contract(r).EvaluateTransaction("GetAssetsByPrefix", prefix)
+	if err != nil {
+		writeFabricError(w, "Failed to evaluate transaction", err)
+		return
+	}
+	log.Printf("<-- Transaction Evaluated: GetAssetsByPrefix")
+
+	h.writeAssetList(w, r, result)
+}
+
+// GetTotalBalanceHandler handles GET /api/assets/total-balance
+func (h *ApiHandler) GetTotalBalanceHandler(w http.ResponseWriter, r *http.Request) {
+	log.Printf("--> Evaluating Transaction: GetTotalBalance")
+	result, err := h.contract(r).EvaluateTransaction("GetTotalBalance")
+	if err != nil {
+		writeFabricError(w, "Failed to evaluate transaction", err)
+		return
+	}
+	log.Printf("<-- Transaction Evaluated: GetTotalBalance")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"total": string(result)})
+}
+
+// DeleteAssetsByStatusHandler handles DELETE /api/assets?status=...&confirm=true
+// The confirm flag guards against wiping assets by accident.
+func (h *ApiHandler) DeleteAssetsByStatusHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	status := strings.TrimSpace(query.Get("status"))
+	if status == "" {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "the status query parameter is required")
+		return
+	}
+	if confirm, err := strconv.ParseBool(query.Get("confirm")); err != nil || !confirm {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "deleting every asset with STATUS "+status+" requires confirm=true")
+		return
+	}
+
+	log.Printf("--> Submitting Transaction: DeleteAssetsByStatus, STATUS: %s", status)
+	result, err := h.submitTransaction(r, "DeleteAssetsByStatus", status)
+	h.ReadCache.clear()
+	if err != nil {
+		writeFabricError(w, "Failed to submit transaction", err)
+		return
+	}
+	log.Printf("<-- Transaction Committed: DeleteAssetsByStatus, STATUS: %s", status)
+
+	deleted, err := strconv.Atoi(string(result))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, codeInternalError, fmt.Sprintf("Failed to parse deleted count: %s", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int{"deleted": deleted})
+}
+
+// GetAssetStatsHandler handles GET /api/assets/stats
+// It returns the asset count and total, average, minimum and maximum balance
+func (h *ApiHandler) GetAssetStatsHandler(w http.ResponseWriter, r *http.Request) {
+	log.Printf("--> Evaluating Transaction: GetAssetStats")
+	result, err := h.contract(r).EvaluateTransaction("GetAssetStats")
+	if err != nil {
+		writeFabricError(w, "Failed to evaluate transaction", err)
+		return
+	}
+	log.Printf("<-- Transaction Evaluated: GetAssetStats")
+
 	w.Header().Set("Content-Type", "application/json")
-	// The result from the chaincode is raw JSON (likely an array), so write it directly
 	w.Write(result)
 }
 
+// ReindexAssetsHandler handles POST /api/admin/reindex
+// It rebuilds the chaincode's secondary indexes from the asset records
+func (h *ApiHandler) ReindexAssetsHandler(w http.ResponseWriter, r *http.Request) {
+	log.Printf("--> Submitting Transaction: ReindexAssets")
+	result, err := h.submitTransaction(r, "ReindexAssets")
+	// Cached status queries may have read the stale indexes
+	h.ReadCache.clear()
+	if err != nil {
+		writeFabricError(w, "Failed to submit transaction", err)
+		return
+	}
+	log.Printf("<-- Transaction Committed: ReindexAssets")
+
+	rebuilt, err := strconv.Atoi(string(result))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, codeInternalError, fmt.Sprintf("Failed to parse rebuilt count: %s", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int{"rebuilt": rebuilt})
+}
+
+// InitLedgerHandler handles POST /api/admin/init
+// It seeds the ledger with the chaincode's sample assets, skipping any that already exist.
+// InitLedger is admin only, so this submits with the org's admin identity.
+func (h *ApiHandler) InitLedgerHandler(w http.ResponseWriter, r *http.Request) {
+	log.Printf("--> Submitting Transaction: InitLedger")
+	result, err := h.submitTransaction(r, "InitLedger")
+	// Any of the sample assets may have been created
+	h.ReadCache.clear()
+	if err != nil {
+		writeFabricError(w, "Failed to submit transaction", err)
+		return
+	}
+	log.Printf("<-- Transaction Committed: InitLedger")
+
+	created, err := strconv.Atoi(string(result))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, codeInternalError, fmt.Sprintf("Failed to parse created count: %s", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int{"created": created})
+}
+
+// MigrateAssetsHandler handles POST /api/admin/migrate (admin only)
+// The body is {"targetVersion": N}. The chaincode rewrites every asset stored
+// with an older schema and the response is its
+// {"targetVersion":N,"migrated":N,"skipped":N,"dealerIds":[...]} result.
+// MigrateAssets is admin only, so this submits with the org's admin identity
+// configured by FABRIC_ADMIN_CERT_<MSPID> and FABRIC_ADMIN_KEY_<MSPID>.
+func (h *ApiHandler) MigrateAssetsHandler(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		TargetVersion int `json:"targetVersion"`
+	}
+	if err := decodeJSONBody(r, &request); err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, err.Error())
+		return
+	}
+	if request.TargetVersion <= 0 {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "targetVersion must be a positive schema version")
+		return
+	}
+
+	log.Printf("--> Submitting Transaction: MigrateAssets, Target Version: %d", request.TargetVersion)
+	result, err := h.submitTransaction(r, "MigrateAssets", strconv.Itoa(request.TargetVersion))
+	// Any asset may have been rewritten
+	h.ReadCache.clear()
+	if err != nil {
+		writeFabricError(w, "Failed to submit transaction", err)
+		return
+	}
+	log.Printf("<-- Transaction Committed: MigrateAssets, Target Version: %d", request.TargetVersion)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(result)
+}
+
+// DepositHandler handles POST /api/assets/{id}/deposit
+func (h *ApiHandler) DepositHandler(w http.ResponseWriter, r *http.Request) {
+	h.balanceChangeHandler(w, r, "Deposit")
+}
+
+// WithdrawHandler handles POST /api/assets/{id}/withdraw
+func (h *ApiHandler) WithdrawHandler(w http.ResponseWriter, r *http.Request) {
+	h.balanceChangeHandler(w, r, "Withdraw")
+}
+
+// balanceChangeHandler submits a Deposit or Withdraw of the AMOUNT in the request body
+func (h *ApiHandler) balanceChangeHandler(w http.ResponseWriter, r *http.Request, name string) {
+	vars := mux.Vars(r)
+	assetID := normalizeDealerID(vars["id"])
+	if !requireDealerID(w, assetID) {
+		return
+	}
+
+	var change struct {
+		AMOUNT string `json:"AMOUNT"` // Receive as string
+	}
+
+	// Decode the JSON request body into our struct
+	if err := decodeJSONBody(r, &change); err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, err.Error())
+		return
+	}
+	amount, err := parseAmount("AMOUNT", change.AMOUNT)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, err.Error())
+		return
+	}
+
+	log.Printf("--> Submitting Transaction: %s, ID: %s", name, assetID)
+	_, err = h.submitTransaction(r, name, assetID, amount)
+	h.invalidateReads(r, assetID)
+	if err != nil {
+		writeFabricError(w, "Failed to submit transaction", err)
+		return
+	}
+
+	log.Printf("<-- Transaction Committed: %s, ID: %s", name, assetID)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": name + " for asset " + assetID + " completed successfully"})
+}
+
+// PostTransactionHandler handles POST /api/assets/{id}/transactions
+// The body is {"AMOUNT":"10.00","TRANSTYPE":"CREDIT"|"DEBIT","REMARKS":"..."}. The
+// chaincode moves BALANCE by the amount and records the transaction on the asset in
+// one step, and the updated asset is returned with 201.
+func (h *ApiHandler) PostTransactionHandler(w http.ResponseWriter, r *http.Request) {
+	assetID := normalizeDealerID(mux.Vars(r)["id"])
+	if !requireDealerID(w, assetID) {
+		return
+	}
+
+	var transaction struct {
+		AMOUNT    string `json:"AMOUNT"`
+		TRANSTYPE string `json:"TRANSTYPE"`
+		REMARKS   string `json:"REMARKS"`
+	}
+	if err := decodeJSONBody(r, &transaction); err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, err.Error())
+		return
+	}
+	amount, err := parseAmount("AMOUNT", transaction.AMOUNT)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, err.Error())
+		return
+	}
+	transType := strings.ToUpper(strings.TrimSpace(transaction.TRANSTYPE))
+	if transType != "CREDIT" && transType != "DEBIT" {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "TRANSTYPE must be CREDIT or DEBIT")
+		return
+	}
+
+	log.Printf("--> Submitting Transaction: PostTransaction, ID: %s, Type: %s", assetID, transType)
+	result, err := h.submitTransaction(r, "PostTransaction", assetID, amount, transType, transaction.REMARKS)
+	h.invalidateReads(r, assetID)
+	if err != nil {
+		writeFabricError(w, "Failed to submit transaction", err)
+		return
+	}
+	log.Printf("<-- Transaction Committed: PostTransaction, ID: %s", assetID)
+
+	asset, err := h.maskAsset(r, result)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, codeInternalError, fmt.Sprintf("Failed to mask asset: %s", err))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	w.Write(asset)
+}
+
+// FreezeAssetHandler handles POST /api/assets/{id}/freeze (admin only)
+func (h *ApiHandler) FreezeAssetHandler(w http.ResponseWriter, r *http.Request) {
+	h.submitForAsset(w, r, "FreezeAsset", "frozen")
+}
+
+// UnfreezeAssetHandler handles POST /api/assets/{id}/unfreeze (admin only)
+func (h *ApiHandler) UnfreezeAssetHandler(w http.ResponseWriter, r *http.Request) {
+	h.submitForAsset(w, r, "UnfreezeAsset", "unfrozen")
+}
+
+// SetAssetStatusHandler handles PATCH /api/assets/{id}/status
+// The body is {"status":"ACTIVE"}. Only STATUS changes; freezing and unfreezing
+// stay with the admin-only freeze endpoints.
+func (h *ApiHandler) SetAssetStatusHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	assetID := normalizeDealerID(vars["id"])
+	if !requireDealerID(w, assetID) {
+		return
+	}
+
+	var body struct {
+		Status string `json:"status"`
+	}
+	if err := decodeJSONBody(r, &body); err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, err.Error())
+		return
+	}
+	status := strings.TrimSpace(body.Status)
+	if status == "" {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "status must not be empty")
+		return
+	}
+	if status == "FROZEN" {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "use POST /api/assets/"+assetID+"/freeze to freeze an asset")
+		return
+	}
+
+	log.Printf("--> Submitting Transaction: SetAssetStatus, ID: %s, Status: %s", assetID, status)
+	_, err := h.submitTransaction(r, "SetAssetStatus", assetID, status)
+	h.invalidateReads(r, assetID)
+	if err != nil {
+		writeFabricError(w, "Failed to submit transaction", err)
+		return
+	}
+
+	log.Printf("<-- Transaction Committed: SetAssetStatus, ID: %s", assetID)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Asset " + assetID + " status set to " + status})
+}
+
+// submitForAsset submits a transaction whose only argument is the asset ID from the URL
+func (h *ApiHandler) submitForAsset(w http.ResponseWriter, r *http.Request, name string, done string) {
+	vars := mux.Vars(r)
+	assetID := normalizeDealerID(vars["id"])
+	if !requireDealerID(w, assetID) {
+		return
+	}
+
+	log.Printf("--> Submitting Transaction: %s, ID: %s", name, assetID)
+	_, err := h.submitTransaction(r, name, assetID)
+	h.invalidateReads(r, assetID)
+	if err != nil {
+		writeFabricError(w, "Failed to submit transaction", err)
+		return
+	}
+
+	log.Printf("<-- Transaction Committed: %s, ID: %s", name, assetID)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Asset " + assetID + " " + done + " successfully"})
+}
+
+// TransferBalanceHandler handles POST /api/transfer
+// It moves an amount between two assets in a single transaction. Assets in
+// different currencies need CONVERSIONRATE, the recipient's units per unit of
+// the sender's, e.g. "83.25".
+func (h *ApiHandler) TransferBalanceHandler(w http.ResponseWriter, r *http.Request) {
+	var transfer struct {
+		FROMDEALERID   string `json:"FROMDEALERID"`
+		TODEALERID     string `json:"TODEALERID"`
+		AMOUNT         string `json:"AMOUNT"` // Receive as string
+		CONVERSIONRATE string `json:"CONVERSIONRATE"`
+	}
+
+	// Decode the JSON request body into our struct
+	if err := decodeJSONBody(r, &transfer); err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, err.Error())
+		return
+	}
+
+	transfer.FROMDEALERID = normalizeDealerID(transfer.FROMDEALERID)
+	transfer.TODEALERID = normalizeDealerID(transfer.TODEALERID)
+
+	// Transfers between currencies have their own transaction, so callers of the
+	// original four argument TransferBalance keep working
+	name := "TransferBalance"
+	args := []string{transfer.FROMDEALERID, transfer.TODEALERID, transfer.AMOUNT}
+	if strings.TrimSpace(transfer.CONVERSIONRATE) != "" {
+		name = "TransferBalanceWithRate"
+		args = append(args, transfer.CONVERSIONRATE)
+	}
+
+	log.Printf("--> Submitting Transaction: %s, From: %s, To: %s", name, transfer.FROMDEALERID, transfer.TODEALERID)
+	_, err := h.submitTransaction(r, name, args...)
+	h.invalidateReads(r, transfer.FROMDEALERID, transfer.TODEALERID)
+	if err != nil {
+		writeFabricError(w, "Failed to submit transaction", err)
+		return
+	}
+
+	log.Printf("<-- Transaction Committed: %s, From: %s, To: %s", name, transfer.FROMDEALERID, transfer.TODEALERID)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Transfer from " + transfer.FROMDEALERID + " to " + transfer.TODEALERID + " completed successfully"})
+}
+
+// normalizeDealerID applies the same policy as the chaincode: surrounding
+// whitespace is trimmed and letters are uppercased, so " d123 " and "D123"
+// address the same asset (and the same read cache entry)
+func normalizeDealerID(dealerID string) string {
+	return strings.ToUpper(strings.TrimSpace(dealerID))
+}
+
+// requireDealerID writes a 400 response and returns false when the dealer ID
+// is empty or only whitespace, so it never reaches the chaincode as a key
+func requireDealerID(w http.ResponseWriter, dealerID string) bool {
+	if strings.TrimSpace(dealerID) == "" {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "DEALERID must not be empty (dealer IDs are trimmed and uppercased)")
+		return false
+	}
+	return true
+}
+
+// missingDealerIDHandler handles /api/assets/ with an empty ID segment
+func missingDealerIDHandler(w http.ResponseWriter, r *http.Request) {
+	requireDealerID(w, "")
+}
+
+// queryInt parses an integer query parameter, returning def when it is absent
+func queryInt(r *http.Request, name string, def int) (int, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return def, nil
+	}
+	return strconv.Atoi(value)
+}
+
+// isSimulation reports whether the caller asked for a dry run, either with
+// the ?simulate=true query parameter or the X-Dry-Run header
+func isSimulation(r *http.Request) bool {
+	if simulate, err := strconv.ParseBool(r.URL.Query().Get("simulate")); err == nil && simulate {
+		return true
+	}
+	dryRun, err := strconv.ParseBool(r.Header.Get("X-Dry-Run"))
+	return err == nil && dryRun
+}
+
+// simulateTransaction runs a transaction through EvaluateTransaction so the
+// chaincode validation and endorsement logic run, but nothing is committed
+func (h *ApiHandler) simulateTransaction(w http.ResponseWriter, r *http.Request, name string, assetID string, args ...string) {
+	h.simulateWithTransient(w, r, name, assetID, nil, args...)
+}
+
+// simulateWithTransient is simulateTransaction with private inputs passed in the transient map
+func (h *ApiHandler) simulateWithTransient(w http.ResponseWriter, r *http.Request, name string, assetID string, transient map[string][]byte, args ...string) {
+	options := []client.ProposalOption{
+		client.WithArguments(args...),
+		client.WithTransient(saltedTransient(transient)),
+	}
+
+	log.Printf("--> Evaluating Transaction (simulation): %s, ID: %s", name, assetID)
+	_, err := h.contract(r).Evaluate(name, options...)
+	if err != nil {
+		writeFabricError(w, "Simulated transaction failed", err)
+		return
+	}
+	log.Printf("<-- Transaction Evaluated (simulation): %s, ID: %s", name, assetID)
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{
+		"message":   "Simulation succeeded, " + name + " for asset " + assetID + " would be committed",
+		"simulated": "true",
+	})
+}
+
+// decodeJSONBody decodes the request body into dst, turning decoder errors
+// into messages that tell the client what is wrong and where
+func decodeJSONBody(r *http.Request, dst any) error {
+	return decodeJSON(r.Body, dst)
+}
+
+// decodeJSON decodes a JSON request body from body, see decodeJSONBody
+func decodeJSON(body io.Reader, dst any) error {
+	err := json.NewDecoder(body).Decode(dst)
+	if err == nil {
+		return nil
+	}
+
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.Is(err, io.EOF):
+		return errors.New("the request body must not be empty")
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return errors.New("the request body contains incomplete JSON")
+	case errors.As(err, &syntaxErr):
+		return fmt.Errorf("the request body contains malformed JSON at position %d", syntaxErr.Offset)
+	case errors.As(err, &typeErr):
+		if typeErr.Field != "" {
+			return fmt.Errorf("invalid value for %s: expected a %s, got a %s", typeErr.Field, typeErr.Type, typeErr.Value)
+		}
+		return fmt.Errorf("the request body must be a JSON object, got a %s", typeErr.Value)
+	default:
+		return fmt.Errorf("invalid request body: %v", err)
+	}
+}
+
+// amountPattern is a decimal amount with at most two decimal places, e.g. 100 or -0.25
+var amountPattern = regexp.MustCompile(`^-?[0-9]+(\.[0-9]{1,2})?$`)
+
+// parseAmount checks that value is a decimal amount with at most two decimal
+// places and returns it trimmed, ready to pass to the chaincode. Amounts are
+// kept as strings end to end so they never go through a float.
+func parseAmount(field string, value string) (string, error) {
+	amount := strings.TrimSpace(value)
+	if !amountPattern.MatchString(amount) {
+		return "", fmt.Errorf("invalid %s: %q is not a decimal amount with at most two decimal places", field, value)
+	}
+	return amount, nil
+}
+
 // --- Helper Functions for Fabric Connection ---
 
-// newGrpcConnection creates a gRPC connection to the peer
-func newGrpcConnection() *grpc.ClientConn {
+// newGrpcConnection creates a gRPC connection to the org's gateway peer
+func newGrpcConnection(org orgConfig) *grpc.ClientConn {
 	// We need to use the full path relative to the /workspaces/ directory
 	// We assume the API is running from 'fabric-samples/asset-manager-api'
 	// So we go up one level and into 'test-network'
-	peerCert, err := os.ReadFile("../test-network/" + tlsCertPath)
+	peers, err := orgPeers(org)
 	if err != nil {
-		panic(fmt.Errorf("failed to load peer TLS certificate: %w", err))
+		panic(err)
 	}
-
-	certPool := x509.NewCertPool()
-	if !certPool.AppendCertsFromPEM(peerCert) {
-		panic("failed to add peer certificate to pool")
+	// A single peer is checked against its host override, failover connections
+	// set the TLS server name per address
+	serverName := ""
+	if len(peers) == 1 {
+		serverName = peers[0].HostOverride
+	}
+	creds, err := peerCredentials(org, peers, serverName)
+	if err != nil {
+		panic(err)
 	}
 
-	transportCredentials := credentials.NewClientTLSFromCert(certPool, gatewayPeer)
-	conn, err := grpc.Dial(peerEndpoint, grpc.WithTransportCredentials(transportCredentials))
+	var conn *grpc.ClientConn
+	if len(peers) > 1 {
+		conn, err = newFailoverConnection(org, peers, creds)
+	} else {
+		conn, err = grpc.Dial(peers[0].Endpoint, grpc.WithTransportCredentials(creds))
+	}
 	if err != nil {
 		panic(fmt.Errorf("failed to create gRPC connection: %w", err))
 	}
+
+	// With FABRIC_DISCOVERY the configured peers only bootstrap the connection
+	if discoveryEnabled() {
+		return withDiscoveredPeers(conn, org, peers)
+	}
 	return conn
 }
 
-// newGateway creates a new Gateway client
-func newGateway(conn *grpc.ClientConn) *client.Gateway {
-	id := newIdentity()
-	sign := newSign()
+// newGateway creates a new Gateway client using the org's identity
+func newGateway(conn *grpc.ClientConn, org orgConfig) *client.Gateway {
+	id := newIdentity(org)
+	sign := newSign(org)
+	timeouts := loadGatewayTimeouts()
+	options := []client.ConnectOption{
+		client.WithClientConnection(conn),
+		client.WithSign(sign),
+		client.WithEvaluateTimeout(timeouts.Evaluate),
+		client.WithEndorseTimeout(timeouts.Endorse),
+		client.WithSubmitTimeout(timeouts.Submit),
+		client.WithCommitStatusTimeout(timeouts.CommitStatus),
+	}
+
+	// With mutual TLS the peers check proposals are bound to our TLS client certificate
+	certificateHash, err := tlsClientCertificateHash(org)
+	if err != nil {
+		panic(err)
+	}
+	if certificateHash != nil {
+		options = append(options, client.WithTLSClientCertificateHash(certificateHash))
+	}
 
 	// ***** THIS IS THE FIX *****
 	// The first argument must be the identity, followed by options.
 	gw, err := client.Connect(
 		id, // 1. The identity (as the first argument)
 		// 2. All other items as options
-		client.WithClientConnection(conn),
-		client.WithSign(sign),
-		client.WithEvaluateTimeout(5*time.Second),
-		client.WithEndorseTimeout(15*time.Second),
-		client.WithSubmitTimeout(5*time.Second),
-		client.WithCommitStatusTimeout(1*time.Minute),
+		options...,
 	)
 	// ***************************
 
@@ -293,11 +1497,11 @@ func newGateway(conn *grpc.ClientConn) *client.Gateway {
 }
 
 // newIdentity creates a client identity for connecting to the Gateway
-func newIdentity() *identity.X509Identity {
+func newIdentity(org orgConfig) *identity.X509Identity {
 	// We need to use the full path relative to the /workspaces/ directory
 	// We assume the API is running from 'fabric-samples/asset-manager-api'
 	// So we go up one level and into 'test-network'
-	certData, err := os.ReadFile("../test-network/" + certPath)
+	certData, err := os.ReadFile(networkPath(org.CertPath))
 	if err != nil {
 		panic(fmt.Errorf("failed to read certificate file: %w", err))
 	}
@@ -307,7 +1511,7 @@ func newIdentity() *identity.X509Identity {
 		panic(err)
 	}
 
-	id, err := identity.NewX509Identity(mspID, cert)
+	id, err := identity.NewX509Identity(org.MSPID, cert)
 	if err != nil {
 		panic(err)
 	}
@@ -315,13 +1519,13 @@ func newIdentity() *identity.X509Identity {
 }
 
 // newSign creates a function that signs transactions
-func newSign() identity.Sign {
+func newSign(org orgConfig) identity.Sign {
 	// We need to use the full path relative to the /workspaces/ directory
 	// We assume the API is running from 'fabric-samples/asset-manager-api'
 	// So we go up one level and into 'test-network'
 
 	// The key file has a random name, so we read the directory
-	files, err := os.ReadDir("../test-network/" + keyPath)
+	files, err := os.ReadDir(networkPath(org.KeyPath))
 	if err != nil {
 		panic(fmt.Errorf("failed to read private key directory: %w", err))
 	}
@@ -329,7 +1533,7 @@ func newSign() identity.Sign {
 		panic("no private key found in directory")
 	}
 	// Use the first key found
-	keyFileData, err := os.ReadFile(path.Join("../test-network/"+keyPath, files[0].Name()))
+	keyFileData, err := os.ReadFile(path.Join(networkPath(org.KeyPath), files[0].Name()))
 	if err != nil {
 		panic(fmt.Errorf("failed to read private key file: %w", err))
 	}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// Limits the chaincode puts on an asset's Metadata, checked here too so a body
+// is rejected before calling Fabric. Lengths are in bytes.
+const (
+	maxMetadataEntries     = 20
+	maxMetadataKeyLength   = 64
+	maxMetadataValueLength = 256
+)
+
+// metadataArg encodes the Metadata of a create or update body as the chaincode's
+// metadataJSON argument. Absent metadata is passed as "", which keeps the stored
+// metadata on update, while an empty object clears it.
+func metadataArg(metadata map[string]string) string {
+	if metadata == nil {
+		return ""
+	}
+	metadataJSON, _ := json.Marshal(metadata)
+	return string(metadataJSON)
+}
+
+// validateMetadataField checks the Metadata of a create or update body: absent,
+// null or an object of strings within the limits
+func validateMetadataField(raw json.RawMessage) []fieldError {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return nil
+	}
+
+	var metadata map[string]string
+	if err := json.Unmarshal(raw, &metadata); err != nil {
+		return []fieldError{{Field: "Metadata", Message: "Metadata must be an object of strings"}}
+	}
+	if len(metadata) > maxMetadataEntries {
+		return []fieldError{{Field: "Metadata", Message: fmt.Sprintf("Metadata may have at most %d entries, got %d", maxMetadataEntries, len(metadata))}}
+	}
+
+	var problems []fieldError
+	for key, value := range metadata {
+		if err := validateMetadataEntry(key, value); err != nil {
+			problems = append(problems, fieldError{Field: "Metadata", Message: err.Error()})
+		}
+	}
+	return problems
+}
+
+// validateMetadataEntry checks a single metadata key and value
+func validateMetadataEntry(key string, value string) error {
+	if strings.TrimSpace(key) == "" {
+		return fmt.Errorf("Metadata keys must not be empty")
+	}
+	if len(key) > maxMetadataKeyLength {
+		return fmt.Errorf("Metadata key %q is longer than %d bytes", key, maxMetadataKeyLength)
+	}
+	if len(value) > maxMetadataValueLength {
+		return fmt.Errorf("Metadata value of %q is longer than %d bytes", key, maxMetadataValueLength)
+	}
+	return nil
+}
+
+// metadataRequest returns the asset and key of a /api/assets/{id}/metadata/{key}
+// request, writing the error response and returning false when they are invalid
+// or the caller may not see the asset's Metadata
+func (h *ApiHandler) metadataRequest(w http.ResponseWriter, r *http.Request) (string, string, bool) {
+	vars := mux.Vars(r)
+	assetID := normalizeDealerID(vars["id"])
+	if !requireDealerID(w, assetID) {
+		return "", "", false
+	}
+	if visible := h.visibleFields(callerRole(r)); visible != nil && !visible["Metadata"] {
+		writeError(w, http.StatusForbidden, codeForbidden, "Metadata is masked for this caller")
+		return "", "", false
+	}
+	return assetID, vars["key"], true
+}
+
+// GetAssetMetadataHandler handles GET /api/assets/{id}/metadata/{key}
+// It returns {"key":"...","value":"..."}, or 404 when the asset has no such key
+func (h *ApiHandler) GetAssetMetadataHandler(w http.ResponseWriter, r *http.Request) {
+	assetID, key, ok := h.metadataRequest(w, r)
+	if !ok {
+		return
+	}
+
+	log.Printf("--> Evaluating Transaction: GetAssetMetadata, ID: %s, Key: %s", assetID, key)
+	result, err := h.contract(r).EvaluateTransaction("GetAssetMetadata", assetID, key)
+	if err != nil {
+		writeFabricError(w, "Failed to evaluate transaction", err)
+		return
+	}
+	log.Printf("<-- Transaction Evaluated: GetAssetMetadata, ID: %s, Key: %s", assetID, key)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"key": key, "value": string(result)})
+}
+
+// SetAssetMetadataHandler handles PUT /api/assets/{id}/metadata/{key}
+// The body is {"value":"..."}. Only that key changes.
+func (h *ApiHandler) SetAssetMetadataHandler(w http.ResponseWriter, r *http.Request) {
+	assetID, key, ok := h.metadataRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var body struct {
+		Value *string `json:"value"`
+	}
+	if err := decodeJSONBody(r, &body); err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, err.Error())
+		return
+	}
+	if body.Value == nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "value is required")
+		return
+	}
+	if err := validateMetadataEntry(key, *body.Value); err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, err.Error())
+		return
+	}
+
+	log.Printf("--> Submitting Transaction: SetAssetMetadata, ID: %s, Key: %s", assetID, key)
+	_, err := h.submitTransaction(r, "SetAssetMetadata", assetID, key, *body.Value)
+	h.invalidateReads(r, assetID)
+	if err != nil {
+		writeFabricError(w, "Failed to submit transaction", err)
+		return
+	}
+	log.Printf("<-- Transaction Committed: SetAssetMetadata, ID: %s, Key: %s", assetID, key)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"key": key, "value": *body.Value})
+}
+
+// DeleteAssetMetadataHandler handles DELETE /api/assets/{id}/metadata/{key}
+func (h *ApiHandler) DeleteAssetMetadataHandler(w http.ResponseWriter, r *http.Request) {
+	assetID, key, ok := h.metadataRequest(w, r)
+	if !ok {
+		return
+	}
+
+	log.Printf("--> Submitting Transaction: DeleteAssetMetadata, ID: %s, Key: %s", assetID, key)
+	_, err := h.submitTransaction(r, "DeleteAssetMetadata", assetID, key)
+	h.invalidateReads(r, assetID)
+	if err != nil {
+		writeFabricError(w, "Failed to submit transaction", err)
+		return
+	}
+	log.Printf("<-- Transaction Committed: DeleteAssetMetadata, ID: %s, Key: %s", assetID, key)
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/hyperledger/fabric-protos-go-apiv2/peer"
+)
+
+// metricsPath serves the API's metrics in the Prometheus text format. Like the
+// probes it is served at the top level without an API key, so scrapers need none.
+const metricsPath = "/metrics"
+
+// apiMetrics holds the counters and gauges served on /metrics
+type apiMetrics struct {
+	// inFlight is the number of API requests being handled right now
+	inFlight atomic.Int64
+	// rejected counts requests turned away because inFlight was at the limit
+	rejected atomic.Int64
+	// maxInFlight is MAX_IN_FLIGHT_REQUESTS, 0 when requests aren't limited
+	maxInFlight int64
+
+	// commitCodes counts the validation codes of the submits the API waited on
+	commitCodesMu sync.Mutex
+	commitCodes   map[string]int64
+}
+
+// newAPIMetrics reads MAX_IN_FLIGHT_REQUESTS, the number of concurrent API
+// requests above which new ones get a 503. Unset or 0 means no limit.
+func newAPIMetrics() *apiMetrics {
+	m := &apiMetrics{commitCodes: make(map[string]int64)}
+	if value := os.Getenv("MAX_IN_FLIGHT_REQUESTS"); value != "" {
+		limit, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || limit < 0 {
+			log.Printf("Ignoring invalid MAX_IN_FLIGHT_REQUESTS %q, requests are not limited", value)
+		} else {
+			m.maxInFlight = limit
+		}
+	}
+	if m.maxInFlight > 0 {
+		log.Printf("Limiting the API to %d in-flight requests", m.maxInFlight)
+	}
+	return m
+}
+
+// recordCommit counts a committed transaction's validation code, such as VALID or
+// MVCC_READ_CONFLICT
+func (m *apiMetrics) recordCommit(code peer.TxValidationCode) {
+	m.commitCodesMu.Lock()
+	defer m.commitCodesMu.Unlock()
+	m.commitCodes[code.String()]++
+}
+
+// untrackedPath reports whether requests to path are left out of the in-flight
+// count: the probes and metrics never reach a peer, and WebSockets stay open
+// for as long as the client is connected
+func untrackedPath(path string) bool {
+	return probePaths[path] || path == metricsPath || strings.HasSuffix(path, "/api/ws")
+}
+
+// inFlightMiddleware counts the requests being handled and, when a limit is set,
+// answers 503 to requests that would go over it so a burst can't pile up on the peers
+func (h *ApiHandler) inFlightMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if untrackedPath(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		inFlight := h.Metrics.inFlight.Add(1)
+		defer h.Metrics.inFlight.Add(-1)
+
+		if h.Metrics.maxInFlight > 0 && inFlight > h.Metrics.maxInFlight {
+			h.Metrics.rejected.Add(1)
+			w.Header().Set("Retry-After", "1")
+			writeError(w, http.StatusServiceUnavailable, codeOverloaded, "Too many requests in flight, try again later")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// MetricsHandler handles GET /metrics
+func (h *ApiHandler) MetricsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+	writeMetric(w, "asset_api_in_flight_requests", "gauge",
+		"API requests currently being handled.", h.Metrics.inFlight.Load())
+	writeMetric(w, "asset_api_in_flight_requests_limit", "gauge",
+		"MAX_IN_FLIGHT_REQUESTS, 0 when unlimited.", h.Metrics.maxInFlight)
+	writeMetric(w, "asset_api_rejected_requests_total", "counter",
+		"Requests answered with 503 because the in-flight limit was reached.", h.Metrics.rejected.Load())
+	h.Metrics.writeCommitCodes(w)
+	if h.Audit != nil {
+		writeMetric(w, "asset_api_audit_queue_depth", "gauge",
+			"Audit log entries waiting to be written.", int64(len(h.Audit.entries)))
+	}
+}
+
+// writeCommitCodes writes the commit validation code counter, one sample per code seen
+func (m *apiMetrics) writeCommitCodes(w http.ResponseWriter) {
+	m.commitCodesMu.Lock()
+	defer m.commitCodesMu.Unlock()
+
+	const name = "asset_api_commit_status_total"
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", name,
+		"Committed submits by validation code. Async submits are not waited on and not counted.", name)
+	codes := make([]string, 0, len(m.commitCodes))
+	for code := range m.commitCodes {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		fmt.Fprintf(w, "%s{code=%q} %d\n", name, code, m.commitCodes[code])
+	}
+}
+
+func writeMetric(w http.ResponseWriter, name string, metricType string, help string, value int64) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %d\n", name, help, name, metricType, name, value)
+}
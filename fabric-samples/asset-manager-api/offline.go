@@ -0,0 +1,288 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+	"github.com/hyperledger/fabric-gateway/pkg/identity"
+	"github.com/hyperledger/fabric-protos-go-apiv2/common"
+	"github.com/hyperledger/fabric-protos-go-apiv2/gateway"
+	"github.com/hyperledger/fabric-protos-go-apiv2/peer"
+	"google.golang.org/protobuf/proto"
+)
+
+// The offline signing flow lets clients sign each step with a key held outside this
+// process, for example in an HSM, instead of the keystore key loaded by newSign.
+// Proposals carry the client's own certificate, so the signatures match it.
+// Every step returns the serialized message and the digest to sign. The client sends
+// the bytes back with its signature, so the server keeps no state between steps.
+// Byte fields are base64 encoded in JSON.
+
+// offlineMessage is a serialized gateway message and the digest the client must sign
+type offlineMessage struct {
+	Bytes  []byte `json:"bytes"`
+	Digest []byte `json:"digest"`
+	TxID   string `json:"txId"`
+}
+
+// offlineSigned is a serialized gateway message returned with the client's signature
+type offlineSigned struct {
+	Bytes     []byte `json:"bytes"`
+	Signature []byte `json:"signature"`
+}
+
+// CreateOfflineProposalHandler handles POST /api/offline/proposals with a
+// {"transaction": "...", "args": [...], "certificate": "...", "mspId": "..."} body.
+// certificate is the PEM certificate of the key the client signs with, and mspId
+// defaults to the request's organization. The proposal is created for that
+// identity on a gateway without a signer, so no local key is involved.
+func (h *ApiHandler) CreateOfflineProposalHandler(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Transaction string   `json:"transaction"`
+		Args        []string `json:"args"`
+		Certificate string   `json:"certificate"`
+		MSPID       string   `json:"mspId"`
+	}
+	if err := decodeJSONBody(r, &request); err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, err.Error())
+		return
+	}
+	if request.Transaction == "" {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "transaction is required")
+		return
+	}
+	if request.Certificate == "" {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "certificate is required")
+		return
+	}
+
+	org := h.requestOrg(r)
+	if request.MSPID == "" {
+		request.MSPID = org
+	}
+	cert, err := identity.CertificateFromPEM([]byte(request.Certificate))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "Invalid certificate: "+err.Error())
+		return
+	}
+	id, err := identity.NewX509Identity(request.MSPID, cert)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "Invalid certificate: "+err.Error())
+		return
+	}
+
+	// Signing happens offline, so the gateway only needs the caller's identity
+	conn := h.orgConns[org]
+	options := []client.ConnectOption{client.WithClientConnection(conn.conn)}
+	certificateHash, err := tlsClientCertificateHash(conn.config)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, codeInternalError, err.Error())
+		return
+	}
+	if certificateHash != nil {
+		options = append(options, client.WithTLSClientCertificateHash(certificateHash))
+	}
+	gw, err := client.Connect(id, options...)
+	if err != nil {
+		writeFabricError(w, "Failed to create proposal", err)
+		return
+	}
+	defer gw.Close()
+
+	channel := requestChannel(r)
+	proposal, err := gw.GetNetwork(channel).GetContract(h.Channels[channel]).NewProposal(request.Transaction,
+		client.WithArguments(request.Args...),
+		client.WithTransient(saltedTransient(nil)),
+	)
+	if err != nil {
+		writeFabricError(w, "Failed to create proposal", err)
+		return
+	}
+	proposalBytes, err := proposal.Bytes()
+	if err != nil {
+		writeFabricError(w, "Failed to serialize proposal", err)
+		return
+	}
+	log.Printf("Created offline proposal: %s, TxID: %s", request.Transaction, proposal.TransactionID())
+
+	writeOfflineMessage(w, offlineMessage{
+		Bytes:  proposalBytes,
+		Digest: proposal.Digest(),
+		TxID:   proposal.TransactionID(),
+	})
+}
+
+// EndorseOfflineProposalHandler handles POST /api/offline/proposals/endorse
+// It endorses a signed proposal and returns the transaction to sign for submission
+func (h *ApiHandler) EndorseOfflineProposalHandler(w http.ResponseWriter, r *http.Request) {
+	signed, ok := decodeOfflineSigned(w, r)
+	if !ok {
+		return
+	}
+
+	proposal, err := h.gateway(h.requestOrg(r)).NewSignedProposal(signed.Bytes, signed.Signature)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "Invalid signed proposal: "+err.Error())
+		return
+	}
+
+	log.Printf("--> Endorsing offline proposal, TxID: %s", proposal.TransactionID())
+	transaction, err := proposal.Endorse()
+	if err != nil {
+		writeFabricError(w, "Failed to endorse proposal", err)
+		return
+	}
+	log.Printf("<-- Offline proposal endorsed, TxID: %s", transaction.TransactionID())
+
+	transactionBytes, err := transaction.Bytes()
+	if err != nil {
+		writeFabricError(w, "Failed to serialize transaction", err)
+		return
+	}
+	writeOfflineMessage(w, offlineMessage{
+		Bytes:  transactionBytes,
+		Digest: transaction.Digest(),
+		TxID:   transaction.TransactionID(),
+	})
+}
+
+// SubmitOfflineTransactionHandler handles POST /api/offline/transactions/submit
+// It submits a signed transaction to the orderer and returns the commit status
+// request to sign. The submit is audited under the signed transaction's name.
+func (h *ApiHandler) SubmitOfflineTransactionHandler(w http.ResponseWriter, r *http.Request) {
+	signed, ok := decodeOfflineSigned(w, r)
+	if !ok {
+		return
+	}
+
+	transaction, err := h.gateway(h.requestOrg(r)).NewSignedTransaction(signed.Bytes, signed.Signature)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "Invalid signed transaction: "+err.Error())
+		return
+	}
+
+	// The audit names the transaction the client signed
+	name, args, err := offlineInvocation(signed.Bytes)
+	if err != nil {
+		log.Printf("Failed to read the offline transaction's invocation, TxID: %s: %v", transaction.TransactionID(), err)
+		name, args = "OfflineTransaction", nil
+	}
+
+	log.Printf("--> Submitting offline transaction: %s, TxID: %s", name, transaction.TransactionID())
+	commit, err := transaction.Submit()
+	// Any asset may have been touched, so drop every cached read
+	h.ReadCache.clear()
+	if err != nil {
+		h.audit(r, name, args, transaction.TransactionID(), auditFailed, err)
+		writeFabricError(w, "Failed to submit transaction", err)
+		return
+	}
+	h.audit(r, name, args, commit.TransactionID(), auditSubmitted, nil)
+	log.Printf("<-- Offline transaction submitted: %s, TxID: %s", name, commit.TransactionID())
+
+	commitBytes, err := commit.Bytes()
+	if err != nil {
+		writeFabricError(w, "Failed to serialize commit status request", err)
+		return
+	}
+	writeOfflineMessage(w, offlineMessage{
+		Bytes:  commitBytes,
+		Digest: commit.Digest(),
+		TxID:   commit.TransactionID(),
+	})
+}
+
+// GetOfflineCommitStatusHandler handles POST /api/offline/commits/status
+// It waits for a submitted transaction to commit using a signed commit status request
+func (h *ApiHandler) GetOfflineCommitStatusHandler(w http.ResponseWriter, r *http.Request) {
+	signed, ok := decodeOfflineSigned(w, r)
+	if !ok {
+		return
+	}
+
+	commit, err := h.gateway(h.requestOrg(r)).NewSignedCommit(signed.Bytes, signed.Signature)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "Invalid signed commit status request: "+err.Error())
+		return
+	}
+
+	status, err := commit.Status()
+	if err != nil {
+		writeFabricError(w, "Failed to get commit status", err)
+		return
+	}
+	log.Printf("<-- Offline transaction status: %s, TxID: %s", status.Code, status.TransactionID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"txId":        status.TransactionID,
+		"blockNumber": status.BlockNumber,
+		"successful":  status.Successful,
+		"code":        status.Code.String(),
+	})
+}
+
+// offlineInvocation unpacks the transaction name and arguments from a serialized
+// prepared transaction
+func offlineInvocation(transactionBytes []byte) (string, []string, error) {
+	prepared := &gateway.PreparedTransaction{}
+	if err := proto.Unmarshal(transactionBytes, prepared); err != nil {
+		return "", nil, err
+	}
+	payload := &common.Payload{}
+	if err := proto.Unmarshal(prepared.GetEnvelope().GetPayload(), payload); err != nil {
+		return "", nil, err
+	}
+	tx := &peer.Transaction{}
+	if err := proto.Unmarshal(payload.GetData(), tx); err != nil {
+		return "", nil, err
+	}
+	if len(tx.GetActions()) == 0 {
+		return "", nil, fmt.Errorf("the transaction has no actions")
+	}
+	actionPayload := &peer.ChaincodeActionPayload{}
+	if err := proto.Unmarshal(tx.GetActions()[0].GetPayload(), actionPayload); err != nil {
+		return "", nil, err
+	}
+	proposalPayload := &peer.ChaincodeProposalPayload{}
+	if err := proto.Unmarshal(actionPayload.GetChaincodeProposalPayload(), proposalPayload); err != nil {
+		return "", nil, err
+	}
+	invocation := &peer.ChaincodeInvocationSpec{}
+	if err := proto.Unmarshal(proposalPayload.GetInput(), invocation); err != nil {
+		return "", nil, err
+	}
+
+	invocationArgs := invocation.GetChaincodeSpec().GetInput().GetArgs()
+	if len(invocationArgs) == 0 {
+		return "", nil, fmt.Errorf("the transaction has no function name")
+	}
+	args := make([]string, 0, len(invocationArgs)-1)
+	for _, arg := range invocationArgs[1:] {
+		args = append(args, string(arg))
+	}
+	return string(invocationArgs[0]), args, nil
+}
+
+// decodeOfflineSigned decodes a signed message body, writing a 400 when it is incomplete
+func decodeOfflineSigned(w http.ResponseWriter, r *http.Request) (offlineSigned, bool) {
+	var signed offlineSigned
+	if err := decodeJSONBody(r, &signed); err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, err.Error())
+		return signed, false
+	}
+	if len(signed.Bytes) == 0 || len(signed.Signature) == 0 {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "bytes and signature are required")
+		return signed, false
+	}
+	return signed, true
+}
+
+// writeOfflineMessage writes a message for the client to sign
+func writeOfflineMessage(w http.ResponseWriter, message offlineMessage) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(message)
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// orgHeader lets a request choose which organization's identity it acts as
+const orgHeader = "X-Fabric-Org"
+
+// orgConfig holds the identity and gateway peer settings for one organization
+// Paths are relative to the 'test-network' directory
+type orgConfig struct {
+	MSPID        string
+	CertPath     string
+	KeyPath      string
+	TLSCertPath  string
+	PeerEndpoint string
+	GatewayPeer  string // Also the TLS server name the peer's certificate is checked against
+
+	// Optional client certificate for peers that require mutual TLS, set by withTLSSettings
+	TLSClientCertPath string
+	TLSClientKeyPath  string
+
+	// Identity admin routes submit with, see withAdminIdentity. AdminFromEnv is
+	// set when it was configured rather than defaulted, so it must load.
+	AdminCertPath string
+	AdminKeyPath  string
+	AdminFromEnv  bool
+}
+
+// knownOrgs are the organizations created by the test-network
+var knownOrgs = map[string]orgConfig{
+	"Org1MSP": newOrgConfig("Org1MSP", "org1.example.com", "7051"),
+	"Org2MSP": newOrgConfig("Org2MSP", "org2.example.com", "9051"),
+}
+
+// newOrgConfig builds the config for a test-network org from its domain,
+// following the standard User1 / Admin / peer0 crypto material layout
+func newOrgConfig(mspID string, domain string, peerPort string) orgConfig {
+	cryptoPath := "organizations/peerOrganizations/" + domain
+	gatewayPeer := "peer0." + domain
+
+	return orgConfig{
+		MSPID:         mspID,
+		CertPath:      cryptoPath + "/users/User1@" + domain + "/msp/signcerts/User1@" + domain + "-cert.pem",
+		KeyPath:       cryptoPath + "/users/User1@" + domain + "/msp/keystore/", // Will find the first key
+		TLSCertPath:   cryptoPath + "/peers/" + gatewayPeer + "/tls/ca.crt",
+		PeerEndpoint:  gatewayPeer + ":" + peerPort,
+		GatewayPeer:   gatewayPeer,
+		AdminCertPath: cryptoPath + "/users/Admin@" + domain + "/msp/signcerts/Admin@" + domain + "-cert.pem",
+		AdminKeyPath:  cryptoPath + "/users/Admin@" + domain + "/msp/keystore/",
+	}
+}
+
+// enabledOrgs returns the organizations listed in FABRIC_ORGS (comma separated),
+// with their TLS environment settings applied. Only Org1MSP is enabled by default.
+func enabledOrgs() ([]orgConfig, error) {
+	names := os.Getenv("FABRIC_ORGS")
+	if names == "" {
+		names = "Org1MSP"
+	}
+
+	var orgs []orgConfig
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		org, ok := knownOrgs[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown organization %q in FABRIC_ORGS", name)
+		}
+		org, err := withTLSSettings(org)
+		if err != nil {
+			return nil, err
+		}
+		org, err = withAdminIdentity(org)
+		if err != nil {
+			return nil, err
+		}
+		orgs = append(orgs, org)
+	}
+	return orgs, nil
+}
+
+// defaultOrgID returns the organization used when a request doesn't pick one
+func defaultOrgID() string {
+	if org := os.Getenv("FABRIC_DEFAULT_ORG"); org != "" {
+		return org
+	}
+	return "Org1MSP"
+}
+
+// requestOrg returns the organization selected by the X-Fabric-Org header,
+// falling back to the default organization
+func (h *ApiHandler) requestOrg(r *http.Request) string {
+	if org := r.Header.Get(orgHeader); org != "" {
+		return org
+	}
+	return h.DefaultOrg
+}
+
+// orgMiddleware rejects requests that select an organization this API has no gateway for
+func (h *ApiHandler) orgMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		org := h.requestOrg(r)
+		if h.gateway(org) == nil {
+			writeError(w, http.StatusBadRequest, codeUnknownOrganization, fmt.Sprintf("Unknown organization %q in %s header", org, orgHeader))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/resolver"
+	"google.golang.org/grpc/resolver/manual"
+)
+
+// peerConfig is one gateway peer an organization can connect through.
+// TLSCertPath is relative to the 'test-network' directory unless it is absolute.
+type peerConfig struct {
+	Endpoint     string
+	TLSCertPath  string
+	HostOverride string
+}
+
+// orgPeers returns the gateway peers for an org, in the order they are tried.
+// FABRIC_PEERS_<MSPID> (e.g. FABRIC_PEERS_ORG1MSP) is a comma separated list of
+// endpoint|tlsCertPath|hostOverride entries, where the last two parts default to
+// the org's TLS certificate and the endpoint's host name. Without it the org's
+// single default peer is used.
+func orgPeers(org orgConfig) ([]peerConfig, error) {
+	name, value := orgEnv(org, "FABRIC_PEERS")
+	if value == "" {
+		return []peerConfig{{
+			Endpoint:     org.PeerEndpoint,
+			TLSCertPath:  org.TLSCertPath,
+			HostOverride: org.GatewayPeer,
+		}}, nil
+	}
+
+	var peers []peerConfig
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.Split(entry, "|")
+		if len(parts) > 3 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid %s entry %q, expected endpoint|tlsCertPath|hostOverride", name, entry)
+		}
+
+		peer := peerConfig{
+			Endpoint:    parts[0],
+			TLSCertPath: org.TLSCertPath,
+		}
+		if len(parts) > 1 && parts[1] != "" {
+			peer.TLSCertPath = parts[1]
+		}
+		if len(parts) > 2 && parts[2] != "" {
+			peer.HostOverride = parts[2]
+		} else {
+			peer.HostOverride, _, _ = strings.Cut(peer.Endpoint, ":")
+		}
+		peers = append(peers, peer)
+	}
+	if len(peers) == 0 {
+		return nil, fmt.Errorf("%s must list at least one peer", name)
+	}
+	return peers, nil
+}
+
+// newFailoverConnection creates one gRPC connection over all of the org's peers.
+// It uses the pick_first policy, so calls go to the first peer that is reachable,
+// and when that connection breaks gRPC reconnects by trying the peers in order.
+func newFailoverConnection(org orgConfig, peers []peerConfig, creds credentials.TransportCredentials) (*grpc.ClientConn, error) {
+	addresses := make([]resolver.Address, 0, len(peers))
+	for _, peer := range peers {
+		// ServerName is the TLS host name checked for this address
+		addresses = append(addresses, resolver.Address{Addr: peer.Endpoint, ServerName: peer.HostOverride})
+	}
+
+	peerResolver := manual.NewBuilderWithScheme("fabric-peers-" + strings.ToLower(org.MSPID))
+	peerResolver.InitialState(resolver.State{Addresses: addresses})
+
+	return grpc.NewClient(peerResolver.Scheme()+":///"+org.MSPID,
+		grpc.WithResolvers(peerResolver),
+		grpc.WithTransportCredentials(creds),
+		grpc.WithDefaultServiceConfig(`{"loadBalancingConfig": [{"pick_first": {}}]}`),
+	)
+}
+
+// peerEndpoints lists the org's peer endpoints for logging, in failover order
+func peerEndpoints(org orgConfig) string {
+	peers, err := orgPeers(org)
+	if err != nil {
+		return org.PeerEndpoint
+	}
+	endpoints := make([]string, len(peers))
+	for i, peer := range peers {
+		endpoints[i] = peer.Endpoint
+	}
+	return strings.Join(endpoints, ", ")
+}
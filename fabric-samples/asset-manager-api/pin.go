@@ -0,0 +1,191 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+)
+
+// mpinTransientKey is the transient map entry the chaincode reads the MPIN from,
+// saltTransientKey the one it reads the random salt for private fields from
+const (
+	mpinTransientKey = "MPIN"
+	saltTransientKey = "SALT"
+)
+
+// mpinTransient puts mpin in a transient map, or returns nil when it is empty
+func mpinTransient(mpin string) map[string][]byte {
+	if mpin == "" {
+		return nil
+	}
+	return map[string][]byte{mpinTransientKey: []byte(mpin)}
+}
+
+// saltedTransient returns a copy of transient with a fresh random salt added.
+// The chaincode can't generate randomness itself and needs the salt whenever it
+// writes the MSISDN or MPIN, so every submit carries one.
+func saltedTransient(transient map[string][]byte) map[string][]byte {
+	salted := make(map[string][]byte, len(transient)+1)
+	for key, value := range transient {
+		salted[key] = value
+	}
+	salted[saltTransientKey] = []byte(rand.Text())
+	return salted
+}
+
+// A dealer is locked out of PIN verification after maxPINFailures failed
+// attempts within pinFailureWindow, which makes brute forcing a PIN impractical
+const (
+	maxPINFailures   = 5
+	pinFailureWindow = 15 * time.Minute
+)
+
+// pinAttempts records recent failed and in-flight PIN verifications per dealer
+type pinAttempts struct {
+	mu       sync.Mutex
+	failures map[string][]time.Time
+}
+
+func newPINAttempts() *pinAttempts {
+	return &pinAttempts{
+		failures: make(map[string][]time.Time),
+	}
+}
+
+// recent returns the dealer's failures within the window, dropping older ones
+func (p *pinAttempts) recent(key string) []time.Time {
+	cutoff := time.Now().Add(-pinFailureWindow)
+	var kept []time.Time
+	for _, failure := range p.failures[key] {
+		if failure.After(cutoff) {
+			kept = append(kept, failure)
+		}
+	}
+	if len(kept) == 0 {
+		delete(p.failures, key)
+	} else {
+		p.failures[key] = kept
+	}
+	return kept
+}
+
+// reserve counts an attempt for the dealer before it is verified, so concurrent
+// requests can't all pass the check and exceed the limit. It reports whether the
+// dealer is locked out instead, and how long until the oldest failure expires.
+// The attempt stays counted as a failure unless it is cleared or released.
+func (p *pinAttempts) reserve(key string) (attempt time.Time, locked bool, retryAfter time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	failures := p.recent(key)
+	if len(failures) >= maxPINFailures {
+		return time.Time{}, true, time.Until(failures[0].Add(pinFailureWindow))
+	}
+	attempt = time.Now()
+	p.failures[key] = append(failures, attempt)
+	return attempt, false, 0
+}
+
+// release uncounts a reserved attempt that couldn't be verified, for example
+// because the peer was unavailable
+func (p *pinAttempts) release(key string, attempt time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	failures := p.failures[key]
+	for i, failure := range failures {
+		if failure.Equal(attempt) {
+			failures = append(failures[:i], failures[i+1:]...)
+			break
+		}
+	}
+	if len(failures) == 0 {
+		delete(p.failures, key)
+	} else {
+		p.failures[key] = failures
+	}
+}
+
+// clear drops the dealer's failures after a successful verification
+func (p *pinAttempts) clear(key string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	delete(p.failures, key)
+}
+
+// failureCount returns the dealer's failures within the window
+func (p *pinAttempts) failureCount(key string) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return len(p.recent(key))
+}
+
+// VerifyPINHandler handles POST /api/assets/{id}/verify-pin with a {"mpin":"..."} body
+// It returns {"valid":true|false} and never echoes the submitted or stored PIN.
+// The lockout only covers this endpoint: clients with their own identity can
+// evaluate the chaincode's VerifyMPIN directly and are not limited by it.
+func (h *ApiHandler) VerifyPINHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	assetID := normalizeDealerID(vars["id"])
+	if !requireDealerID(w, assetID) {
+		return
+	}
+
+	var request struct {
+		MPIN string `json:"mpin"`
+	}
+	if err := decodeJSONBody(r, &request); err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, err.Error())
+		return
+	}
+	if request.MPIN == "" {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "mpin is required")
+		return
+	}
+
+	// Dealer IDs are only unique per channel
+	key := readCacheKey(r, assetID)
+	attempt, locked, retryAfter := h.PINAttempts.reserve(key)
+	if locked {
+		w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter.Seconds())+1))
+		writeError(w, http.StatusTooManyRequests, codeTooManyAttempts, fmt.Sprintf("Too many failed PIN attempts for asset %s, try again later", assetID))
+		return
+	}
+
+	log.Printf("--> Evaluating Transaction: VerifyMPIN, ID: %s", assetID)
+	result, err := h.contract(r).Evaluate("VerifyMPIN",
+		client.WithArguments(assetID),
+		client.WithTransient(mpinTransient(request.MPIN)),
+	)
+	if err != nil {
+		h.PINAttempts.release(key, attempt)
+		writeFabricError(w, "Failed to evaluate transaction", err)
+		return
+	}
+	log.Printf("<-- Transaction Evaluated: VerifyMPIN, ID: %s", assetID)
+
+	valid, err := strconv.ParseBool(string(result))
+	if err != nil {
+		h.PINAttempts.release(key, attempt)
+		writeError(w, http.StatusInternalServerError, codeInternalError, fmt.Sprintf("Failed to parse PIN verification result: %s", err))
+		return
+	}
+	if valid {
+		h.PINAttempts.clear(key)
+	} else {
+		log.Printf("Failed PIN verification for asset %s (%d of %d allowed in %s)", assetID, h.PINAttempts.failureCount(key), maxPINFailures, pinFailureWindow)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]bool{"valid": valid})
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// loadPrivateDataOrgs parses PRIVATE_DATA_ORGS, the comma separated MSP IDs whose
+// identities may read the private asset details. It defaults to Org1MSP, the only
+// member of the chaincode's private data collection.
+func loadPrivateDataOrgs() (map[string]bool, error) {
+	value := os.Getenv("PRIVATE_DATA_ORGS")
+	if value == "" {
+		value = "Org1MSP"
+	}
+
+	orgs := make(map[string]bool)
+	for _, mspID := range strings.Split(value, ",") {
+		mspID = strings.TrimSpace(mspID)
+		if mspID == "" {
+			continue
+		}
+		if _, ok := knownOrgs[mspID]; !ok {
+			return nil, fmt.Errorf("unknown organization %q in PRIVATE_DATA_ORGS", mspID)
+		}
+		orgs[mspID] = true
+	}
+	return orgs, nil
+}
+
+// ReadAssetPrivateHandler handles GET /api/assets/{id}/private
+// It returns the MSISDN and MPIN hash from the private data collection. Restricted
+// callers and organizations outside PRIVATE_DATA_ORGS are refused, and the
+// result is never cached.
+func (h *ApiHandler) ReadAssetPrivateHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	assetID := normalizeDealerID(vars["id"])
+	if !requireDealerID(w, assetID) {
+		return
+	}
+
+	if callerRole(r) == roleRestricted {
+		writeError(w, http.StatusForbidden, codeForbidden, "Private asset details are not available to restricted callers")
+		return
+	}
+	if org := h.requestOrg(r); !h.PrivateDataOrgs[org] {
+		writeError(w, http.StatusForbidden, codeForbidden, fmt.Sprintf("Organization %s may not read private asset details", org))
+		return
+	}
+
+	log.Printf("--> Evaluating Transaction: ReadAssetPrivate, ID: %s", assetID)
+	result, err := h.contract(r).EvaluateTransaction("ReadAssetPrivate", assetID)
+	if err != nil {
+		writeFabricError(w, "Failed to evaluate transaction", err)
+		return
+	}
+	log.Printf("<-- Transaction Evaluated: ReadAssetPrivate, ID: %s", assetID)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(result)
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/hyperledger/fabric-protos-go-apiv2/common"
+	"google.golang.org/protobuf/proto"
+)
+
+// qsccName is the system chaincode that answers ledger queries such as block lookups
+const qsccName = "qscc"
+
+// assetMeta is the provenance of an asset's current state
+type assetMeta struct {
+	Channel     string    `json:"channel"`
+	TxID        string    `json:"txId"`
+	BlockNumber uint64    `json:"blockNumber"`
+	Timestamp   time.Time `json:"timestamp"`
+}
+
+// writeAssetWithMeta handles ReadAsset with ?includeMeta=true
+// It wraps the asset with the transaction and block of its last write:
+// {"asset":{...},"meta":{"channel":"...","txId":"...","blockNumber":N,"timestamp":"..."}}
+func (h *ApiHandler) writeAssetWithMeta(w http.ResponseWriter, r *http.Request, assetID string, result []byte) {
+	log.Printf("--> Evaluating Transaction: GetLatestAssetTransaction, ID: %s", assetID)
+	latestJSON, err := h.contract(r).EvaluateTransaction("GetLatestAssetTransaction", assetID)
+	if err != nil {
+		writeFabricError(w, "Failed to evaluate transaction", err)
+		return
+	}
+	log.Printf("<-- Transaction Evaluated: GetLatestAssetTransaction, ID: %s", assetID)
+
+	var latest struct {
+		TxID      string    `json:"txId"`
+		Timestamp time.Time `json:"timestamp"`
+	}
+	if len(latestJSON) == 0 {
+		writeError(w, http.StatusNotFound, codeAssetNotFound, fmt.Sprintf("No history found for asset %s", assetID))
+		return
+	}
+	if err := json.Unmarshal(latestJSON, &latest); err != nil {
+		writeError(w, http.StatusInternalServerError, codeInternalError, fmt.Sprintf("Failed to parse history: %s", err))
+		return
+	}
+
+	blockNumber, err := h.blockNumberForTx(r, latest.TxID)
+	if err != nil {
+		writeFabricError(w, "Failed to look up block", err)
+		return
+	}
+
+	asset, err := h.maskAsset(r, result)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, codeInternalError, fmt.Sprintf("Failed to mask asset: %s", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"asset": asset,
+		"meta": assetMeta{
+			Channel:     requestChannel(r),
+			TxID:        latest.TxID,
+			BlockNumber: blockNumber,
+			Timestamp:   latest.Timestamp,
+		},
+	})
+}
+
+// blockNumberForTx returns the number of the block holding the transaction on the request's channel
+func (h *ApiHandler) blockNumberForTx(r *http.Request, txID string) (uint64, error) {
+	org := h.requestOrg(r)
+	channel := requestChannel(r)
+	qscc := h.contractFor(org, channel, qsccName)
+
+	log.Printf("--> Evaluating Transaction: GetBlockByTxID, TxID: %s", txID)
+	blockBytes, err := qscc.EvaluateTransaction("GetBlockByTxID", channel, txID)
+	if err != nil {
+		return 0, err
+	}
+	log.Printf("<-- Transaction Evaluated: GetBlockByTxID, TxID: %s", txID)
+
+	var block common.Block
+	if err := proto.Unmarshal(blockBytes, &block); err != nil {
+		return 0, fmt.Errorf("failed to parse block: %w", err)
+	}
+	return block.GetHeader().GetNumber(), nil
+}
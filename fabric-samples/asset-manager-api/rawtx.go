@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// loadRawTransactions reads RAW_TX_FUNCTIONS, the comma separated chaincode
+// functions admins may call through POST /api/tx. The endpoint is only served when it is set.
+func loadRawTransactions() map[string]bool {
+	value := os.Getenv("RAW_TX_FUNCTIONS")
+	if value == "" {
+		return nil
+	}
+
+	functions := make(map[string]bool)
+	for _, function := range strings.Split(value, ",") {
+		if function = strings.TrimSpace(function); function != "" {
+			functions[function] = true
+		}
+	}
+	if len(functions) > 0 {
+		log.Printf("Raw transaction endpoint enabled for %d functions", len(functions))
+	}
+	return functions
+}
+
+// RawTransactionHandler handles POST /api/tx with a
+// {"function": "...", "args": [...], "submit": true|false} body. It submits or
+// evaluates a chaincode function the API has no dedicated route for and writes
+// the chaincode result as it is. Only the functions in RAW_TX_FUNCTIONS may be called.
+func (h *ApiHandler) RawTransactionHandler(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Function string   `json:"function"`
+		Args     []string `json:"args"`
+		Submit   bool     `json:"submit"`
+	}
+	if err := decodeJSONBody(r, &request); err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, err.Error())
+		return
+	}
+	if request.Function == "" {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "function is required")
+		return
+	}
+	if !h.RawTransactions[request.Function] {
+		writeError(w, http.StatusForbidden, codeForbidden, fmt.Sprintf("function %s is not allowed, see RAW_TX_FUNCTIONS", request.Function))
+		return
+	}
+
+	if !request.Submit {
+		log.Printf("--> Evaluating Raw Transaction: %s", request.Function)
+		result, err := h.contract(r).EvaluateTransaction(request.Function, request.Args...)
+		if err != nil {
+			writeFabricError(w, "Failed to evaluate transaction", err)
+			return
+		}
+		log.Printf("<-- Raw Transaction Evaluated: %s", request.Function)
+		writeRawResult(w, result)
+		return
+	}
+
+	h.writable(func(w http.ResponseWriter, r *http.Request) {
+		log.Printf("--> Submitting Raw Transaction: %s", request.Function)
+		result, err := h.submitTransaction(r, request.Function, request.Args...)
+		// The API can't tell which assets the function wrote
+		h.ReadCache.clear()
+		if err != nil {
+			writeFabricError(w, "Failed to submit transaction", err)
+			return
+		}
+		log.Printf("<-- Raw Transaction Committed: %s", request.Function)
+		writeRawResult(w, result)
+	})(w, r)
+}
+
+// writeRawResult writes a chaincode result unchanged, as JSON when it is JSON
+func writeRawResult(w http.ResponseWriter, result []byte) {
+	if len(result) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if json.Valid(result) {
+		w.Header().Set("Content-Type", "application/json")
+	} else {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	}
+	w.Write(result)
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// readCache is a short-lived in-memory cache of ReadAsset results for hot keys.
+// Entries are dropped whenever a submit touches the asset. It is off by default,
+// and a nil *readCache is a valid, disabled cache.
+type readCache struct {
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[string]cachedRead
+}
+
+type cachedRead struct {
+	result  []byte
+	expires time.Time
+}
+
+// newReadCache creates a cache whose entries live for READ_CACHE_TTL.
+// It returns nil, disabling the cache, when READ_CACHE_TTL is unset or zero.
+func newReadCache() *readCache {
+	value := os.Getenv("READ_CACHE_TTL")
+	if value == "" {
+		return nil
+	}
+	ttl, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Ignoring invalid READ_CACHE_TTL %q, read cache disabled", value)
+		return nil
+	}
+	if ttl <= 0 {
+		return nil
+	}
+
+	log.Printf("Read cache enabled with a TTL of %s", ttl)
+	return &readCache{
+		ttl:     ttl,
+		entries: make(map[string]cachedRead),
+	}
+}
+
+// get returns a cached result that hasn't expired yet
+func (c *readCache) get(key string) ([]byte, bool) {
+	if c == nil {
+		return nil, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(entry.expires) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return entry.result, true
+}
+
+// put stores a result for the cache TTL
+func (c *readCache) put(key string, result []byte) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[key] = cachedRead{
+		result:  result,
+		expires: time.Now().Add(c.ttl),
+	}
+}
+
+// invalidate drops the cached results for the given keys
+func (c *readCache) invalidate(keys ...string) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, key := range keys {
+		delete(c.entries, key)
+	}
+}
+
+// clear drops every cached result, for submits that may touch any asset
+func (c *readCache) clear() {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = make(map[string]cachedRead)
+}
+
+// readCacheKey identifies an asset on the request's channel. Every org reads
+// the same world state, so the org isn't part of the key.
+func readCacheKey(r *http.Request, assetID string) string {
+	return channelCacheKey(requestChannel(r), assetID)
+}
+
+// channelCacheKey identifies an asset on a channel, see readCacheKey
+func channelCacheKey(channel string, assetID string) string {
+	return channel + "|" + assetID
+}
+
+// invalidateReads drops cached reads of the given assets after a submit.
+// Handlers call it whatever the outcome, since a failed commit status
+// doesn't prove the transaction wasn't committed.
+func (h *ApiHandler) invalidateReads(r *http.Request, assetIDs ...string) {
+	keys := make([]string, 0, len(assetIDs))
+	for _, assetID := range assetIDs {
+		keys = append(keys, readCacheKey(r, assetID))
+	}
+	h.ReadCache.invalidate(keys...)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"sync/atomic"
+)
+
+// readOnlyMode blocks submits while still serving evaluates, so writes can be
+// quiesced during ledger migrations. It starts from READ_ONLY and can be
+// toggled at runtime through POST /api/admin/readonly.
+type readOnlyMode struct {
+	enabled atomic.Bool
+}
+
+// newReadOnlyMode reads the initial state from READ_ONLY
+func newReadOnlyMode() *readOnlyMode {
+	mode := &readOnlyMode{}
+	if value := os.Getenv("READ_ONLY"); value != "" {
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			log.Printf("Ignoring invalid READ_ONLY %q, read-only mode disabled", value)
+		}
+		mode.enabled.Store(enabled)
+	}
+	if mode.enabled.Load() {
+		log.Println("Read-only mode enabled, submit requests will be rejected")
+	}
+	return mode
+}
+
+// writable rejects the request with 503 while read-only mode is on.
+// Dry runs only evaluate, so they are still allowed.
+func (h *ApiHandler) writable(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if h.ReadOnly.enabled.Load() && !isSimulation(r) {
+			w.Header().Set("Retry-After", "60")
+			writeError(w, http.StatusServiceUnavailable, codeReadOnly, "the API is in read-only mode, writes are temporarily disabled")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// SetReadOnlyHandler handles POST /api/admin/readonly with a {"readOnly": true|false} body
+func (h *ApiHandler) SetReadOnlyHandler(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		ReadOnly *bool `json:"readOnly"`
+	}
+	if err := decodeJSONBody(r, &request); err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, err.Error())
+		return
+	}
+	if request.ReadOnly == nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "readOnly is required")
+		return
+	}
+
+	h.ReadOnly.enabled.Store(*request.ReadOnly)
+	log.Printf("Read-only mode set to %t", *request.ReadOnly)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]bool{"readOnly": *request.ReadOnly})
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"runtime/debug"
+	"strconv"
+)
+
+// loadPanicRecovery reads PANIC_RECOVERY and PANIC_STACK_TRACES. Recovery is on
+// unless PANIC_RECOVERY=false, which leaves panics to net/http, and the stack is
+// logged with each panic unless PANIC_STACK_TRACES=false.
+func loadPanicRecovery() (enabled bool, stackTraces bool) {
+	enabled, stackTraces = true, true
+	if value := os.Getenv("PANIC_RECOVERY"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			log.Printf("Ignoring invalid PANIC_RECOVERY %q, recovery stays enabled", value)
+		} else {
+			enabled = parsed
+		}
+	}
+	if value := os.Getenv("PANIC_STACK_TRACES"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			log.Printf("Ignoring invalid PANIC_STACK_TRACES %q, stack traces stay enabled", value)
+		} else {
+			stackTraces = parsed
+		}
+	}
+	return enabled, stackTraces
+}
+
+// recoveryWriter remembers whether the response has started, after which a 500
+// can no longer be sent
+type recoveryWriter struct {
+	http.ResponseWriter
+	started bool
+}
+
+func (rw *recoveryWriter) WriteHeader(status int) {
+	// Informational responses such as 100 Continue don't start the response
+	if status >= http.StatusOK {
+		rw.started = true
+	}
+	rw.ResponseWriter.WriteHeader(status)
+}
+
+func (rw *recoveryWriter) Write(b []byte) (int, error) {
+	rw.started = true
+	return rw.ResponseWriter.Write(b)
+}
+
+// Flush keeps streaming handlers working through the wrapper
+func (rw *recoveryWriter) Flush() {
+	if flusher, ok := rw.ResponseWriter.(http.Flusher); ok {
+		rw.started = true
+		flusher.Flush()
+	}
+}
+
+// Hijack lets the WebSocket endpoint take over the connection through the wrapper
+func (rw *recoveryWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := rw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("the response writer does not support hijacking")
+	}
+	rw.started = true
+	return hijacker.Hijack()
+}
+
+// recoveryMiddleware turns a panicking handler into a 500 with the usual error
+// envelope instead of a dropped connection. The panic is logged at error level
+// with the request's correlation ID and, when stackTraces is set, its stack.
+// When the response had already started the panic can only be logged.
+func recoveryMiddleware(next http.Handler, stackTraces bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rw := &recoveryWriter{ResponseWriter: w}
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+			// net/http uses ErrAbortHandler to abort a response on purpose
+			if recovered == http.ErrAbortHandler {
+				panic(recovered)
+			}
+
+			attrs := []any{
+				"method", r.Method,
+				"path", r.URL.Path,
+				"panic", fmt.Sprint(recovered),
+			}
+			if correlationID := r.Header.Get(correlationIDHeader); correlationID != "" {
+				attrs = append(attrs, "correlationId", correlationID)
+			}
+			if stackTraces {
+				attrs = append(attrs, "stack", string(debug.Stack()))
+			}
+			slog.Error("handler panic", attrs...)
+
+			if rw.started {
+				return
+			}
+			writeError(w, http.StatusInternalServerError, codeInternalError, "The server hit an unexpected error handling this request")
+		}()
+
+		next.ServeHTTP(rw, r)
+	})
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"os"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// apiBasePath returns API_BASE_PATH normalized to "/prefix" form,
+// or "" to serve the routes at the root
+func apiBasePath() string {
+	basePath := strings.Trim(strings.TrimSpace(os.Getenv("API_BASE_PATH")), "/")
+	if basePath == "" {
+		return ""
+	}
+	return "/" + basePath
+}
+
+// registerRoutes adds all API routes to r
+func (h *ApiHandler) registerRoutes(r *mux.Router) {
+	r.HandleFunc("/version", h.VersionHandler).Methods("GET")
+	r.HandleFunc("/api/assets", h.writable(h.idempotent(h.CreateAssetHandler))).Methods("POST")
+	// Fixed paths must be registered before /api/assets/{id} so they aren't taken as an ID
+	r.HandleFunc("/api/assets/total-balance", h.GetTotalBalanceHandler).Methods("GET")
+	r.HandleFunc("/api/assets/stats", h.GetAssetStatsHandler).Methods("GET")
+	r.HandleFunc("/api/assets/search", h.SearchAssetsHandler).Methods("GET")
+	r.HandleFunc("/api/assets/batch-read", h.BatchReadAssetsHandler).Methods("POST")
+	r.HandleFunc("/api/assets/changes", h.GetAssetChangesHandler).Methods("GET")
+	r.HandleFunc("/api/assets/{id}", h.ReadAssetHandler).Methods("GET")
+	r.HandleFunc("/api/assets/{id}", h.HeadAssetHandler).Methods("HEAD")
+	r.HandleFunc("/api/assets/{id}/exists", h.AssetExistsHandler).Methods("GET")
+	r.HandleFunc("/api/assets/history/{id}", h.GetAssetHistoryHandler).Methods("GET")
+	r.HandleFunc("/api/assets/{id}/history.csv", h.ExportAssetHistoryCSVHandler).Methods("GET")
+	r.HandleFunc("/api/assets/{id}/latest", h.GetLatestAssetTransactionHandler).Methods("GET")
+	r.HandleFunc("/api/assets/{id}/at", h.GetAssetAtTimeHandler).Methods("GET")
+	r.HandleFunc("/api/assets/{id}/statement", h.GetAssetStatementHandler).Methods("GET")
+	r.HandleFunc("/api/assets/{id}/archived", h.GetArchivedAssetHandler).Methods("GET")
+	r.HandleFunc("/api/assets/{id}/private", h.ReadAssetPrivateHandler).Methods("GET")
+	r.HandleFunc("/api/assets/{id}", h.writable(h.UpdateAssetHandler)).Methods("PUT")
+	r.HandleFunc("/api/assets/{id}/upsert", h.writable(h.UpsertAssetHandler)).Methods("PUT")
+	r.HandleFunc("/api/assets/{id}/metadata/{key}", h.GetAssetMetadataHandler).Methods("GET")
+	r.HandleFunc("/api/assets/{id}/metadata/{key}", h.writable(h.SetAssetMetadataHandler)).Methods("PUT")
+	r.HandleFunc("/api/assets/{id}/metadata/{key}", h.writable(h.DeleteAssetMetadataHandler)).Methods("DELETE")
+	r.HandleFunc("/api/assets/{id}", h.writable(h.PatchAssetHandler)).Methods("PATCH")
+	r.HandleFunc("/api/assets/{id}/status", h.writable(h.SetAssetStatusHandler)).Methods("PATCH")
+	r.HandleFunc("/api/assets/{id}", h.writable(h.DeleteAssetHandler)).Methods("DELETE")
+	r.HandleFunc("/api/assets", h.GetAllAssetsHandler).Methods("GET")
+	r.HandleFunc("/api/assets", adminOnly(h.writable(h.DeleteAssetsByStatusHandler))).Methods("DELETE")
+	r.HandleFunc("/api/assets.csv", h.ExportAssetsCSVHandler).Methods("GET")
+	r.HandleFunc("/api/assets/", missingDealerIDHandler)
+	r.HandleFunc("/api/assets/{id}/deposit", h.writable(h.DepositHandler)).Methods("POST")
+	r.HandleFunc("/api/assets/{id}/withdraw", h.writable(h.WithdrawHandler)).Methods("POST")
+	r.HandleFunc("/api/assets/{id}/transactions", h.writable(h.PostTransactionHandler)).Methods("POST")
+	r.HandleFunc("/api/assets/{id}/verify-pin", h.VerifyPINHandler).Methods("POST")
+	r.HandleFunc("/api/assets/{id}/freeze", adminOnly(h.writable(h.FreezeAssetHandler))).Methods("POST")
+	r.HandleFunc("/api/assets/{id}/unfreeze", adminOnly(h.writable(h.UnfreezeAssetHandler))).Methods("POST")
+	r.HandleFunc("/api/transfer", h.writable(h.TransferBalanceHandler)).Methods("POST")
+	r.HandleFunc("/api/transfer/batch", h.writable(h.BatchTransferHandler)).Methods("POST")
+	r.HandleFunc("/api/export", h.ExportHistoryHandler).Methods("GET")
+	r.HandleFunc("/api/ws", h.AssetEventsSocketHandler).Methods("GET")
+	r.HandleFunc("/api/events", h.AssetEventsStreamHandler).Methods("GET")
+	r.HandleFunc("/api/ledger/info", h.GetLedgerInfoHandler).Methods("GET")
+	r.HandleFunc("/api/transactions/{txId}/status", h.GetTransactionStatusHandler).Methods("GET")
+	r.HandleFunc("/api/offline/proposals", h.CreateOfflineProposalHandler).Methods("POST")
+	r.HandleFunc("/api/offline/proposals/endorse", h.writable(h.EndorseOfflineProposalHandler)).Methods("POST")
+	r.HandleFunc("/api/offline/transactions/submit", h.writable(h.SubmitOfflineTransactionHandler)).Methods("POST")
+	r.HandleFunc("/api/offline/commits/status", h.GetOfflineCommitStatusHandler).Methods("POST")
+	r.HandleFunc("/api/admin/readonly", adminOnly(h.SetReadOnlyHandler)).Methods("POST")
+	r.HandleFunc("/api/admin/reindex", adminOnly(h.writable(h.ReindexAssetsHandler))).Methods("POST")
+	r.HandleFunc("/api/admin/init", adminOnly(h.writable(h.InitLedgerHandler))).Methods("POST")
+	r.HandleFunc("/api/admin/migrate", adminOnly(h.writable(h.MigrateAssetsHandler))).Methods("POST")
+	r.HandleFunc("/api/admin/reload-identity", adminOnly(h.ReloadIdentityHandler)).Methods("POST")
+	r.HandleFunc("/api/debug/endorse", adminOnly(h.DebugEndorseHandler)).Methods("POST")
+	if len(h.RawTransactions) > 0 {
+		r.HandleFunc("/api/tx", adminOnly(h.RawTransactionHandler)).Methods("POST")
+	}
+	if len(h.WebhookSecret) > 0 {
+		r.HandleFunc(webhookPath, h.writable(h.WebhookHandler)).Methods("POST")
+	}
+}
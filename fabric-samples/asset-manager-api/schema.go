@@ -0,0 +1,235 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// schemaVersionHeader selects how create and update bodies are decoded, so
+// clients built against an older Asset schema keep working,
+// e.g. "X-Schema-Version: 1". Requests without it use the latest schema.
+const schemaVersionHeader = "X-Schema-Version"
+
+// latestSchemaVersion is the current request body schema: the upper case field
+// names of the chaincode Asset, with amounts sent as decimal strings
+const latestSchemaVersion = 2
+
+// legacyFieldNames maps each older schema version's field names to the current ones.
+// Version 1 used camelCase names and sent amounts as JSON numbers. It had no
+// currency, see legacyDefaultCurrency, but accepts one as "currency".
+var legacyFieldNames = map[int]map[string]string{
+	1: {
+		"dealerId":    "DEALERID",
+		"msisdn":      "MSISDN",
+		"mpin":        "MPIN",
+		"balance":     "BALANCE",
+		"status":      "STATUS",
+		"transAmount": "TRANSAMOUNT",
+		"transType":   "TRANSTYPE",
+		"remarks":     "REMARKS",
+		"currency":    "Currency",
+	},
+}
+
+// legacyDefaultCurrency returns DEFAULT_CURRENCY, the Currency given to assets
+// created with an older schema that had none. Without it such creates must send
+// a currency, as the chaincode requires one.
+func legacyDefaultCurrency() string {
+	return strings.ToUpper(strings.TrimSpace(os.Getenv("DEFAULT_CURRENCY")))
+}
+
+// requestSchemaVersion returns the version named by X-Schema-Version, or the
+// latest one when the header is absent
+func requestSchemaVersion(r *http.Request) (int, error) {
+	value := strings.TrimSpace(r.Header.Get(schemaVersionHeader))
+	if value == "" {
+		return latestSchemaVersion, nil
+	}
+
+	version, err := strconv.Atoi(value)
+	if err != nil || version < 1 || version > latestSchemaVersion {
+		return 0, fmt.Errorf("unsupported %s %q, expected a version from 1 to %d", schemaVersionHeader, value, latestSchemaVersion)
+	}
+	return version, nil
+}
+
+// Operations an asset body is validated for, see assetSchema
+const (
+	opCreateAsset = "create"
+	opUpdateAsset = "update"
+)
+
+// assetFieldRule declares one field of the create and update bodies. Every field
+// is a JSON string; null counts as absent.
+type assetFieldRule struct {
+	Name     string
+	Required map[string]bool // Operations the field must be given and non-blank for
+	Pattern  *regexp.Regexp  // Non-blank values must match, after trimming
+	Hint     string          // What Pattern expects, for error messages
+}
+
+// statusPattern matches the STATUS values the chaincode accepts
+var statusPattern = regexp.MustCompile(`^[A-Z][A-Z0-9_]*$`)
+
+// currencyPattern is the shape of an ISO 4217 code. The chaincode checks it's a
+// supported and allowed currency.
+var currencyPattern = regexp.MustCompile(`^[A-Za-z]{3}$`)
+
+// assetSchema mirrors the chaincode's checks on CreateAsset and UpdateAsset
+// arguments, so a body is rejected with all of its problems before calling Fabric.
+// A blank STATUS on create takes the configured default, a blank Currency on
+// update keeps the stored one. The Metadata object is checked separately by
+// validateMetadataField.
+var assetSchema = []assetFieldRule{
+	{Name: "DEALERID", Required: map[string]bool{opCreateAsset: true}},
+	{Name: "MSISDN", Required: map[string]bool{opCreateAsset: true, opUpdateAsset: true}},
+	{Name: "MPIN"},
+	{Name: "BALANCE", Required: map[string]bool{opCreateAsset: true, opUpdateAsset: true}, Pattern: amountPattern, Hint: "a decimal amount with at most two decimal places"},
+	{Name: "STATUS", Required: map[string]bool{opUpdateAsset: true}, Pattern: statusPattern, Hint: "an uppercase word such as ACTIVE"},
+	{Name: "TRANSAMOUNT", Required: map[string]bool{opCreateAsset: true, opUpdateAsset: true}, Pattern: amountPattern, Hint: "a decimal amount with at most two decimal places"},
+	{Name: "TRANSTYPE"},
+	{Name: "REMARKS"},
+	{Name: "Currency", Required: map[string]bool{opCreateAsset: true}, Pattern: currencyPattern, Hint: "a three letter ISO 4217 code such as USD"},
+}
+
+// assetValidationError lists every schema problem of an asset body
+type assetValidationError struct {
+	Fields []fieldError
+}
+
+func (e *assetValidationError) Error() string {
+	messages := make([]string, 0, len(e.Fields))
+	for _, field := range e.Fields {
+		messages = append(messages, field.Message)
+	}
+	return "invalid asset: " + strings.Join(messages, "; ")
+}
+
+// validateAssetFields checks a body's fields against assetSchema for the operation,
+// collecting every problem rather than stopping at the first
+func validateAssetFields(fields map[string]json.RawMessage, operation string) error {
+	var problems []fieldError
+	for _, rule := range assetSchema {
+		raw := bytes.TrimSpace(fields[rule.Name])
+		var value string
+		if len(raw) > 0 && !bytes.Equal(raw, []byte("null")) {
+			if err := json.Unmarshal(raw, &value); err != nil {
+				problems = append(problems, fieldError{Field: rule.Name, Message: fmt.Sprintf("%s must be a string", rule.Name)})
+				continue
+			}
+		}
+
+		trimmed := strings.TrimSpace(value)
+		switch {
+		case trimmed == "" && rule.Required[operation]:
+			problems = append(problems, fieldError{Field: rule.Name, Message: fmt.Sprintf("%s is required", rule.Name)})
+		case trimmed != "" && rule.Pattern != nil && !rule.Pattern.MatchString(trimmed):
+			problems = append(problems, fieldError{Field: rule.Name, Message: fmt.Sprintf("%s must be %s, got %q", rule.Name, rule.Hint, value)})
+		}
+	}
+	problems = append(problems, validateMetadataField(fields["Metadata"])...)
+
+	if len(problems) > 0 {
+		return &assetValidationError{Fields: problems}
+	}
+	return nil
+}
+
+// isBlankField reports whether a raw body field is absent, null or a blank string
+func isBlankField(raw json.RawMessage) bool {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return true
+	}
+	var value string
+	return json.Unmarshal(raw, &value) == nil && strings.TrimSpace(value) == ""
+}
+
+// writeAssetBodyError writes the 400 for a body decodeAssetBody rejected,
+// listing each invalid field when it failed schema validation
+func writeAssetBodyError(w http.ResponseWriter, err error) {
+	var validationErr *assetValidationError
+	if errors.As(err, &validationErr) {
+		writeErrorBody(w, http.StatusBadRequest, errorBody{
+			Code:    codeInvalidRequest,
+			Message: err.Error(),
+			Fields:  validationErr.Fields,
+		})
+		return
+	}
+	writeError(w, http.StatusBadRequest, codeInvalidRequest, err.Error())
+}
+
+// decodeAssetBody decodes a create or update body into dst, which uses the
+// latest field names, after validating it against assetSchema for the operation.
+// Older payloads have their fields renamed, and numeric amounts turned into
+// decimal strings, first.
+func decodeAssetBody(r *http.Request, operation string, dst any) error {
+	version, err := requestSchemaVersion(r)
+	if err != nil {
+		return err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := decodeJSONBody(r, &fields); err != nil {
+		return err
+	}
+	if fields == nil {
+		return fmt.Errorf("the request body must be a JSON object")
+	}
+
+	current := fields
+	if version != latestSchemaVersion {
+		renames := legacyFieldNames[version]
+		current = make(map[string]json.RawMessage, len(fields))
+		for name, value := range fields {
+			if renamed, ok := renames[name]; ok {
+				name = renamed
+			}
+			if _, duplicate := current[name]; duplicate {
+				return fmt.Errorf("the field %s is given more than once", name)
+			}
+			current[name] = value
+		}
+
+		// Amounts were JSON numbers; keep their literal digits rather than parsing
+		// them as floats, so parseAmount sees exactly what the client sent
+		for _, name := range []string{"BALANCE", "TRANSAMOUNT"} {
+			value := bytes.TrimSpace(current[name])
+			if len(value) > 0 && value[0] != '"' && !bytes.Equal(value, []byte("null")) {
+				quoted, err := json.Marshal(string(value))
+				if err != nil {
+					return err
+				}
+				current[name] = quoted
+			}
+		}
+
+		if operation == opCreateAsset && isBlankField(current["Currency"]) {
+			if currency := legacyDefaultCurrency(); currency != "" {
+				quoted, err := json.Marshal(currency)
+				if err != nil {
+					return err
+				}
+				current["Currency"] = quoted
+			}
+		}
+	}
+
+	if err := validateAssetFields(current, operation); err != nil {
+		return err
+	}
+
+	currentJSON, err := json.Marshal(current)
+	if err != nil {
+		return err
+	}
+	return decodeJSON(bytes.NewReader(currentJSON), dst)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// assetSearchResult is an asset matched by the chaincode's SearchAssets
+type assetSearchResult struct {
+	Asset         *Asset   `json:"asset"`
+	MatchedFields []string `json:"matchedFields"`
+}
+
+// SearchAssetsHandler handles GET /api/assets/search?q=
+// It returns the assets whose REMARKS or STATUS contain q, ignoring case, as
+// [{"asset":{...},"matchedFields":["REMARKS"]}]. Restricted callers only get
+// matches on fields they may see, so the search can't reveal masked values.
+func (h *ApiHandler) SearchAssetsHandler(w http.ResponseWriter, r *http.Request) {
+	text := strings.TrimSpace(r.URL.Query().Get("q"))
+	if text == "" {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "q must not be empty")
+		return
+	}
+
+	log.Printf("--> Evaluating Transaction: SearchAssets, Query: %q", text)
+	result, err := h.contract(r).EvaluateTransaction("SearchAssets", text)
+	if err != nil {
+		writeFabricError(w, "Failed to evaluate transaction", err)
+		return
+	}
+	log.Printf("<-- Transaction Evaluated: SearchAssets")
+
+	w.Header().Set("Content-Type", "application/json")
+	visible := h.visibleFields(callerRole(r))
+	if visible == nil {
+		w.Write(result)
+		return
+	}
+
+	var matches []assetSearchResult
+	if len(result) > 0 {
+		if err := json.Unmarshal(result, &matches); err != nil {
+			writeError(w, http.StatusInternalServerError, codeInternalError, fmt.Sprintf("Failed to parse search results: %s", err))
+			return
+		}
+	}
+	filtered := make([]map[string]any, 0, len(matches))
+	for _, match := range matches {
+		var matchedFields []string
+		for _, field := range match.MatchedFields {
+			if visible[field] {
+				matchedFields = append(matchedFields, field)
+			}
+		}
+		if len(matchedFields) == 0 {
+			continue
+		}
+
+		asset, err := filterAsset(match.Asset, visible)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, codeInternalError, fmt.Sprintf("Failed to mask asset: %s", err))
+			return
+		}
+		filtered = append(filtered, map[string]any{"asset": asset, "matchedFields": matchedFields})
+	}
+	json.NewEncoder(w).Encode(filtered)
+}
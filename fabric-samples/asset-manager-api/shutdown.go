@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"time"
+)
+
+// shutdownTimeout bounds how long a graceful shutdown waits for in-flight requests,
+// and then for the event listener, before the process exits anyway
+const shutdownTimeout = 15 * time.Second
+
+// serve runs the server until ctx is canceled by SIGINT or SIGTERM, then shuts it
+// down gracefully, waits for the event listener to stop and flushes the audit log.
+// Request contexts are derived from ctx, so event streams and WebSockets end with
+// it instead of holding up the shutdown.
+func (h *ApiHandler) serve(ctx context.Context, server *http.Server) error {
+	server.BaseContext = func(net.Listener) context.Context { return ctx }
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+	log.Println("Shutting down, waiting for in-flight requests")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Server did not shut down cleanly: %v", err)
+	}
+	if err := <-serveErr; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	if h.Events != nil {
+		if h.Events.wait(shutdownCtx) {
+			log.Println("Event listener stopped")
+		} else {
+			log.Println("Event listener did not stop within the shutdown timeout")
+		}
+	}
+	if h.Audit != nil {
+		if err := h.Audit.close(); err != nil {
+			log.Printf("Audit log did not close cleanly: %v", err)
+		}
+	}
+	log.Println("Server stopped")
+	return nil
+}
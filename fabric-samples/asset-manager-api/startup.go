@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// logEffectiveConfig logs the settings the API runs with once at boot, so a wrong
+// channel or peer shows up before the first request. Secrets, such as the API keys
+// and the webhook secret, are only reported as configured or not.
+func (h *ApiHandler) logEffectiveConfig(orgs []orgConfig) {
+	channels := make([]string, 0, len(h.Channels))
+	for channel, chaincode := range h.Channels {
+		channels = append(channels, channel+"="+chaincode)
+	}
+	sort.Strings(channels)
+
+	var readCacheTTL string
+	if h.ReadCache != nil {
+		readCacheTTL = h.ReadCache.ttl.String()
+	}
+	var eventCheckpoints string
+	if h.Events != nil {
+		eventCheckpoints = h.Events.checkpointDir
+	}
+	var auditLog string
+	if h.Audit != nil {
+		auditLog = h.Audit.path
+	}
+
+	slog.Info("Effective configuration",
+		"defaultOrg", h.DefaultOrg,
+		"channels", strings.Join(channels, ","),
+		"basePath", apiBasePath(),
+		"legacyDefaultCurrency", legacyDefaultCurrency(),
+		"readOnly", h.ReadOnly.enabled.Load(),
+		"apiKeys", len(h.APIKeys),
+		"webhookSecret", redacted(len(h.WebhookSecret) > 0),
+		"auditLog", auditLog,
+		"readCacheTTL", readCacheTTL,
+		"eventListener", h.Events != nil,
+		"eventCheckpointDir", eventCheckpoints,
+		"idempotencyTTL", h.Idempotency.ttl,
+		"maxInFlightRequests", h.Metrics.maxInFlight,
+		"discovery", discoveryEnabled(),
+		"insecure", insecureMode(),
+	)
+
+	for _, org := range orgs {
+		slog.Info("Organization configuration",
+			"mspId", org.MSPID,
+			"peers", peerEndpoints(org),
+			"tlsServerName", org.GatewayPeer,
+			"certPath", displayPath(org.CertPath),
+			"keyPath", displayPath(org.KeyPath),
+			"adminCertPath", displayPath(org.AdminCertPath),
+			"mutualTLS", org.TLSClientCertPath != "",
+		)
+	}
+
+	timeouts := loadGatewayTimeouts()
+	slog.Info("Timeout configuration",
+		"evaluate", timeouts.Evaluate,
+		"endorse", timeouts.Endorse,
+		"submit", timeouts.Submit,
+		"commitStatus", timeouts.CommitStatus,
+		"readiness", readinessTimeout,
+		"discovery", discoveryTimeout,
+		"commitRetryDelay", commitRetryDelay,
+	)
+}
+
+// redacted reports whether a secret is set without revealing it
+func redacted(set bool) string {
+	if set {
+		return "[set]"
+	}
+	return "[unset]"
+}
+
+// checkChaincodes evaluates GetVersion on every allowed channel through the default
+// org's gateway, so a wrong channel or chaincode name stops the API at boot instead
+// of failing every request. SKIP_STARTUP_CHECK=true skips it, e.g. to start the API
+// before the network is up.
+func (h *ApiHandler) checkChaincodes() error {
+	if skip, _ := strconv.ParseBool(os.Getenv("SKIP_STARTUP_CHECK")); skip {
+		log.Println("Skipping the startup chaincode check")
+		return nil
+	}
+
+	channels := make([]string, 0, len(h.Channels))
+	for channel := range h.Channels {
+		channels = append(channels, channel)
+	}
+	sort.Strings(channels)
+
+	for _, channel := range channels {
+		chaincode := h.Channels[channel]
+		contract := h.contractFor(h.DefaultOrg, channel, chaincode)
+
+		ctx, cancel := context.WithTimeout(context.Background(), readinessTimeout)
+		version, err := contract.EvaluateWithContext(ctx, "GetVersion")
+		cancel()
+		if err != nil {
+			return fmt.Errorf("chaincode %s is not reachable on channel %s through %s, check the channel and chaincode names and that the chaincode is committed: %s",
+				chaincode, channel, h.DefaultOrg, strings.Join(fabricErrorMessages(err), "; "))
+		}
+		slog.Info("Chaincode reachable", "channel", channel, "chaincode", chaincode, "version", string(version))
+	}
+	return nil
+}
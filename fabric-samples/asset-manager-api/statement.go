@@ -0,0 +1,278 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// historyRecord is one record of a GetAssetHistoryPage result
+type historyRecord struct {
+	Record    *Asset    `json:"record"`
+	TxID      string    `json:"txId"`
+	Timestamp time.Time `json:"timestamp"`
+	IsDelete  bool      `json:"isDelete"`
+}
+
+// Statement is an account statement for a period: the balance at its start, the
+// transactions that changed the balance during it and the balance at its end
+type Statement struct {
+	DealerID       string           `json:"dealerId"`
+	Currency       string           `json:"currency,omitempty"` // As of the end of the statement
+	From           *time.Time       `json:"from,omitempty"`     // Unset when the statement starts with the asset
+	To             time.Time        `json:"to"`
+	OpeningBalance string           `json:"openingBalance"`
+	ClosingBalance string           `json:"closingBalance"`
+	TotalCredits   string           `json:"totalCredits"`
+	TotalDebits    string           `json:"totalDebits"`
+	Transactions   []StatementEntry `json:"transactions"`
+}
+
+// StatementEntry is a history record that changed the balance. Change is signed,
+// Balance is the balance after it.
+type StatementEntry struct {
+	TxID        string    `json:"txId"`
+	Timestamp   time.Time `json:"timestamp"`
+	TRANSTYPE   string    `json:"TRANSTYPE,omitempty"`
+	TRANSAMOUNT string    `json:"TRANSAMOUNT,omitempty"`
+	REMARKS     string    `json:"REMARKS,omitempty"`
+	Change      string    `json:"change"`
+	Balance     string    `json:"balance"`
+	IsDelete    bool      `json:"isDelete,omitempty"` // Deleting the asset leaves a balance of 0.00
+}
+
+// statementDateLayout is the date only form accepted for ?from= and ?to=
+const statementDateLayout = "2006-01-02"
+
+// parseStatementTime parses a ?from= or ?to= value, an RFC3339 timestamp or a
+// date. A date means the start of that day in UTC, or its end when endOfDay is
+// set so ?to=2024-01-31 covers the whole day.
+func parseStatementTime(value string, endOfDay bool) (time.Time, error) {
+	if at, err := time.Parse(time.RFC3339, value); err == nil {
+		return at, nil
+	}
+	day, err := time.Parse(statementDateLayout, value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if endOfDay {
+		return day.AddDate(0, 0, 1).Add(-time.Nanosecond), nil
+	}
+	return day, nil
+}
+
+// amountCents converts a decimal amount from the chaincode to cents
+func amountCents(amount string) (int64, error) {
+	amount = strings.TrimSpace(amount)
+	if amount == "" {
+		return 0, nil
+	}
+	if !amountPattern.MatchString(amount) {
+		return 0, fmt.Errorf("%q is not a decimal amount", amount)
+	}
+
+	negative := strings.HasPrefix(amount, "-")
+	whole, fraction, _ := strings.Cut(strings.TrimPrefix(amount, "-"), ".")
+	fraction = (fraction + "00")[:2]
+	cents, err := strconv.ParseInt(whole+fraction, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a decimal amount: %v", amount, err)
+	}
+	if negative {
+		cents = -cents
+	}
+	return cents, nil
+}
+
+// formatCents formats cents as a decimal amount with two places
+func formatCents(cents int64) string {
+	sign := ""
+	if cents < 0 {
+		sign, cents = "-", -cents
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, cents/100, cents%100)
+}
+
+// readBoundedAssetHistory reads the asset's history with GetBoundedAssetHistory,
+// at most the chaincode's MaxHistoryRecords records of it, and reports whether
+// there was more along with that maximum
+func (h *ApiHandler) readBoundedAssetHistory(r *http.Request, assetID string) ([]historyRecord, bool, int, error) {
+	log.Printf("--> Evaluating Transaction: GetBoundedAssetHistory, ID: %s", assetID)
+	result, err := h.contract(r).EvaluateTransaction("GetBoundedAssetHistory", assetID, "false")
+	if err != nil {
+		return nil, false, 0, err
+	}
+	log.Printf("<-- Transaction Evaluated: GetBoundedAssetHistory, ID: %s", assetID)
+
+	var history struct {
+		Records    []historyRecord `json:"records"`
+		Truncated  bool            `json:"truncated"`
+		MaxRecords int             `json:"maxRecords"`
+	}
+	if err := json.Unmarshal(result, &history); err != nil {
+		return nil, false, 0, fmt.Errorf("failed to parse history: %w", err)
+	}
+	return history.Records, history.Truncated, history.MaxRecords, nil
+}
+
+// writeHistoryTooLarge rejects a whole-history export of an asset whose history
+// is longer than the chaincode's MaxHistoryRecords
+func writeHistoryTooLarge(w http.ResponseWriter, assetID string, maxRecords int) {
+	writeError(w, http.StatusUnprocessableEntity, codeHistoryTooLarge,
+		fmt.Sprintf("The history of asset %s has more than %d records, the chaincode's MaxHistoryRecords; page through it with GET /api/assets/history/%s?limit=&offset=", assetID, maxRecords, assetID))
+}
+
+// buildStatement walks the history in timestamp order. Records before from give
+// the opening balance, records up to to the transactions and closing balance.
+func buildStatement(dealerID string, records []historyRecord, from time.Time, to time.Time) (*Statement, error) {
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].Timestamp.Before(records[j].Timestamp)
+	})
+
+	statement := &Statement{DealerID: dealerID, To: to, Transactions: []StatementEntry{}}
+	if !from.IsZero() {
+		statement.From = &from
+	}
+
+	var balance, opening, credits, debits int64
+	for _, record := range records {
+		if record.Timestamp.After(to) {
+			break
+		}
+
+		next := int64(0)
+		if !record.IsDelete && record.Record != nil {
+			cents, err := amountCents(record.Record.BALANCE)
+			if err != nil {
+				return nil, fmt.Errorf("invalid BALANCE in transaction %s: %v", record.TxID, err)
+			}
+			next = cents
+		}
+		change := next - balance
+		balance = next
+		if record.Record != nil && record.Record.Currency != "" {
+			statement.Currency = record.Record.Currency
+		}
+
+		if record.Timestamp.Before(from) {
+			opening = balance
+			continue
+		}
+		if change == 0 && !record.IsDelete {
+			continue
+		}
+
+		entry := StatementEntry{
+			TxID:      record.TxID,
+			Timestamp: record.Timestamp,
+			Change:    formatCents(change),
+			Balance:   formatCents(balance),
+			IsDelete:  record.IsDelete,
+		}
+		if record.Record != nil && !record.IsDelete {
+			entry.TRANSTYPE = record.Record.TRANSTYPE
+			entry.TRANSAMOUNT = record.Record.TRANSAMOUNT
+			entry.REMARKS = record.Record.REMARKS
+		}
+		statement.Transactions = append(statement.Transactions, entry)
+
+		if change > 0 {
+			credits += change
+		} else {
+			debits -= change
+		}
+	}
+
+	statement.OpeningBalance = formatCents(opening)
+	statement.ClosingBalance = formatCents(balance)
+	statement.TotalCredits = formatCents(credits)
+	statement.TotalDebits = formatCents(debits)
+	return statement, nil
+}
+
+// GetAssetStatementHandler handles GET /api/assets/{id}/statement?from=&to=
+// from and to are RFC3339 timestamps or dates, a date in to covers the whole
+// day. The statement starts with the asset when from is absent and ends now when
+// to is absent. It's built from the asset's whole history, so histories longer
+// than the chaincode's MaxHistoryRecords are rejected with 422 rather than read
+// without bound.
+func (h *ApiHandler) GetAssetStatementHandler(w http.ResponseWriter, r *http.Request) {
+	assetID := normalizeDealerID(mux.Vars(r)["id"])
+	if !requireDealerID(w, assetID) {
+		return
+	}
+
+	visible := h.visibleFields(callerRole(r))
+	if visible != nil && !visible["BALANCE"] {
+		writeError(w, http.StatusForbidden, codeForbidden, "BALANCE is masked for this caller")
+		return
+	}
+
+	query := r.URL.Query()
+	var from time.Time
+	if value := query.Get("from"); value != "" {
+		parsed, err := parseStatementTime(value, false)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, codeInvalidRequest, "from must be an RFC3339 timestamp or a YYYY-MM-DD date")
+			return
+		}
+		from = parsed
+	}
+	to := time.Now().UTC()
+	if value := query.Get("to"); value != "" {
+		parsed, err := parseStatementTime(value, true)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, codeInvalidRequest, "to must be an RFC3339 timestamp or a YYYY-MM-DD date")
+			return
+		}
+		to = parsed
+	}
+	if to.Before(from) {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "to must not be before from")
+		return
+	}
+
+	records, truncated, maxRecords, err := h.readBoundedAssetHistory(r, assetID)
+	if err != nil {
+		writeFabricError(w, "Failed to evaluate transaction", err)
+		return
+	}
+	if truncated {
+		writeHistoryTooLarge(w, assetID, maxRecords)
+		return
+	}
+	if len(records) == 0 {
+		writeError(w, http.StatusNotFound, codeAssetNotFound, fmt.Sprintf("No history found for asset %s", assetID))
+		return
+	}
+
+	statement, err := buildStatement(assetID, records, from, to)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, codeInternalError, fmt.Sprintf("Failed to build the statement: %s", err))
+		return
+	}
+	if visible != nil {
+		for i := range statement.Transactions {
+			entry := &statement.Transactions[i]
+			if !visible["TRANSTYPE"] {
+				entry.TRANSTYPE = ""
+			}
+			if !visible["TRANSAMOUNT"] {
+				entry.TRANSAMOUNT = ""
+			}
+			if !visible["REMARKS"] {
+				entry.REMARKS = ""
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(statement)
+}
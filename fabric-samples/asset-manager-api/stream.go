@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+// streamPageSize is how many assets each GetAssetsPage call fetches while streaming
+const streamPageSize = 100
+
+// streamAllAssets handles GET /api/assets?stream=true
+// It fetches the ledger a page at a time and writes the JSON array incrementally,
+// flushing after each page, so the response is chunked and the API never holds
+// more than one page in memory.
+func (h *ApiHandler) streamAllAssets(w http.ResponseWriter, r *http.Request) {
+	contract := h.contract(r)
+	visible := h.visibleFields(callerRole(r))
+	flusher, _ := w.(http.Flusher)
+
+	bookmark := ""
+	started := false
+	first := true
+	for {
+		log.Printf("--> Evaluating Transaction: GetAssetsPage, bookmark: %q", bookmark)
+		result, err := contract.EvaluateTransaction("GetAssetsPage", strconv.Itoa(streamPageSize), bookmark)
+		if err != nil {
+			if !started {
+				writeFabricError(w, "Failed to evaluate transaction", err)
+				return
+			}
+			// The response has already started, so all we can do is stop the stream
+			log.Printf("Asset stream stopped, failed to get page: %s", err)
+			return
+		}
+
+		var page struct {
+			Assets   []*Asset `json:"assets"`
+			Bookmark string   `json:"bookmark"`
+			HasMore  bool     `json:"hasMore"`
+		}
+		if err := json.Unmarshal(result, &page); err != nil {
+			if !started {
+				writeError(w, http.StatusInternalServerError, codeInternalError, "Failed to parse assets: "+err.Error())
+				return
+			}
+			log.Printf("Asset stream stopped, failed to parse page: %s", err)
+			return
+		}
+
+		if !started {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte("["))
+			started = true
+		}
+		for _, asset := range page.Assets {
+			var item any = asset
+			if visible != nil {
+				fields, err := filterAsset(asset, visible)
+				if err != nil {
+					log.Printf("Asset stream stopped, failed to mask asset: %s", err)
+					return
+				}
+				item = fields
+			}
+			itemJSON, err := json.Marshal(item)
+			if err != nil {
+				log.Printf("Asset stream stopped, failed to encode asset: %s", err)
+				return
+			}
+			if !first {
+				w.Write([]byte(","))
+			}
+			w.Write(itemJSON)
+			first = false
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+
+		if !page.HasMore || page.Bookmark == "" || page.Bookmark == bookmark {
+			break
+		}
+		bookmark = page.Bookmark
+	}
+	log.Printf("<-- Asset stream finished")
+
+	w.Write([]byte("]"))
+}
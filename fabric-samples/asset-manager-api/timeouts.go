@@ -0,0 +1,42 @@
+package main
+
+import (
+	"log"
+	"os"
+	"time"
+)
+
+// gatewayTimeouts are the per-call deadlines the Fabric Gateway client applies
+type gatewayTimeouts struct {
+	Evaluate     time.Duration
+	Endorse      time.Duration
+	Submit       time.Duration
+	CommitStatus time.Duration
+}
+
+// loadGatewayTimeouts reads FABRIC_EVALUATE_TIMEOUT, FABRIC_ENDORSE_TIMEOUT,
+// FABRIC_SUBMIT_TIMEOUT and FABRIC_COMMIT_STATUS_TIMEOUT as Go durations such
+// as "30s", falling back to 5s, 15s, 5s and 1m respectively
+func loadGatewayTimeouts() gatewayTimeouts {
+	return gatewayTimeouts{
+		Evaluate:     envDuration("FABRIC_EVALUATE_TIMEOUT", 5*time.Second),
+		Endorse:      envDuration("FABRIC_ENDORSE_TIMEOUT", 15*time.Second),
+		Submit:       envDuration("FABRIC_SUBMIT_TIMEOUT", 5*time.Second),
+		CommitStatus: envDuration("FABRIC_COMMIT_STATUS_TIMEOUT", 1*time.Minute),
+	}
+}
+
+// envDuration parses the named variable as a positive duration, or returns
+// fallback when it is unset or invalid
+func envDuration(name string, fallback time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		log.Printf("Ignoring invalid %s %q, using %s", name, value, fallback)
+		return fallback
+	}
+	return duration
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// orgEnv returns the per-organization setting prefix_<MSPID>, e.g. FABRIC_TLS_SERVER_NAME_ORG1MSP
+func orgEnv(org orgConfig, prefix string) (string, string) {
+	name := prefix + "_" + strings.ToUpper(org.MSPID)
+	return name, os.Getenv(name)
+}
+
+// networkPath resolves a crypto material path relative to the 'test-network'
+// directory. Absolute paths, such as mounted secrets, are used as they are.
+func networkPath(p string) string {
+	if filepath.IsAbs(p) {
+		return p
+	}
+	return testNetworkPath + p
+}
+
+// withTLSSettings applies the org's TLS environment settings:
+//
+//	FABRIC_TLS_SERVER_NAME_<MSPID>  the name the peer's TLS certificate is checked against,
+//	                                instead of the gateway peer's host name
+//	FABRIC_TLS_CLIENT_CERT_<MSPID>  a client certificate and key to present to the peers,
+//	FABRIC_TLS_CLIENT_KEY_<MSPID>   for peers that require mutual TLS; both or neither
+func withTLSSettings(org orgConfig) (orgConfig, error) {
+	if _, serverName := orgEnv(org, "FABRIC_TLS_SERVER_NAME"); serverName != "" {
+		org.GatewayPeer = serverName
+	}
+
+	certName, certPath := orgEnv(org, "FABRIC_TLS_CLIENT_CERT")
+	keyName, keyPath := orgEnv(org, "FABRIC_TLS_CLIENT_KEY")
+	if (certPath == "") != (keyPath == "") {
+		return org, fmt.Errorf("%s and %s must be set together", certName, keyName)
+	}
+	org.TLSClientCertPath = certPath
+	org.TLSClientKeyPath = keyPath
+	return org, nil
+}
+
+// insecureMode reports whether INSECURE=true asks for plaintext connections to
+// the peers, for local networks that run without TLS
+func insecureMode() bool {
+	enabled, _ := strconv.ParseBool(os.Getenv("INSECURE"))
+	return enabled
+}
+
+// peerCredentials returns the transport credentials for the org's peers: TLS
+// trusting their CAs, and extraRoots, with serverName checked against the peer
+// certificate. Failover connections leave serverName empty and set it per address.
+// In insecure mode no TLS file is read and the connection is plaintext.
+func peerCredentials(org orgConfig, peers []peerConfig, serverName string, extraRoots ...[]byte) (credentials.TransportCredentials, error) {
+	if insecureMode() {
+		return insecure.NewCredentials(), nil
+	}
+
+	tlsConfig, err := peerTLSConfig(org, peers)
+	if err != nil {
+		return nil, err
+	}
+	for _, cert := range extraRoots {
+		tlsConfig.RootCAs.AppendCertsFromPEM(cert)
+	}
+	tlsConfig.ServerName = serverName
+	return credentials.NewTLS(tlsConfig), nil
+}
+
+// peerTLSConfig returns the TLS config for connecting to the org's peers. It
+// trusts the TLS CA of every peer and, when configured, presents the org's
+// client certificate. ServerName is left to the caller.
+func peerTLSConfig(org orgConfig, peers []peerConfig) (*tls.Config, error) {
+	certPool := x509.NewCertPool()
+	for _, peer := range peers {
+		peerCert, err := os.ReadFile(networkPath(peer.TLSCertPath))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load TLS certificate for peer %s: %w", peer.Endpoint, err)
+		}
+		if !certPool.AppendCertsFromPEM(peerCert) {
+			return nil, fmt.Errorf("failed to add TLS certificate for peer %s to pool", peer.Endpoint)
+		}
+	}
+
+	config := &tls.Config{
+		RootCAs:    certPool,
+		MinVersion: tls.VersionTLS12,
+	}
+	if org.TLSClientCertPath != "" {
+		clientCert, err := tls.LoadX509KeyPair(networkPath(org.TLSClientCertPath), networkPath(org.TLSClientKeyPath))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load TLS client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{clientCert}
+	}
+	return config, nil
+}
+
+// tlsClientCertificateHash returns the SHA-256 hash of the org's TLS client
+// certificate, which the gateway binds into proposals when mutual TLS is used,
+// or nil when no client certificate is configured or TLS is off
+func tlsClientCertificateHash(org orgConfig) ([]byte, error) {
+	if org.TLSClientCertPath == "" || insecureMode() {
+		return nil, nil
+	}
+	clientCert, err := tls.LoadX509KeyPair(networkPath(org.TLSClientCertPath), networkPath(org.TLSClientKeyPath))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS client certificate: %w", err)
+	}
+	hash := sha256.Sum256(clientCert.Certificate[0])
+	return hash[:], nil
+}
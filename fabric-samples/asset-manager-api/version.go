@@ -0,0 +1,37 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+)
+
+// Build information, injected at build time with
+// -ldflags "-X main.version=1.2.3 -X main.commit=$(git rev-parse --short HEAD)"
+var (
+	version = "dev"
+	commit  = "unknown"
+)
+
+// VersionHandler handles GET /version
+// It reports the API build and the channel and chaincode the request addresses
+func (h *ApiHandler) VersionHandler(w http.ResponseWriter, r *http.Request) {
+	channel := requestChannel(r)
+	info := map[string]string{
+		"version":   version,
+		"commit":    commit,
+		"channel":   channel,
+		"chaincode": h.Channels[channel],
+	}
+
+	// The chaincode version is best effort, older chaincode doesn't expose it
+	result, err := h.contract(r).EvaluateTransaction("GetVersion")
+	if err != nil {
+		log.Printf("Failed to get chaincode version: %s", err)
+	} else {
+		info["chaincodeVersion"] = string(result)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(info)
+}
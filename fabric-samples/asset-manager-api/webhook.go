@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// webhookSignatureHeader carries the hex HMAC-SHA256 of the raw request body,
+// keyed with WEBHOOK_SECRET, e.g. "X-Webhook-Signature: sha256=3f1c..."
+const webhookSignatureHeader = "X-Webhook-Signature"
+
+// webhookPath is authenticated by its signature rather than an API key
+const webhookPath = "/api/webhook"
+
+// maxWebhookBodyBytes bounds how much of a webhook body is read before verifying it
+const maxWebhookBodyBytes = 1 << 20
+
+// loadWebhookSecret reads WEBHOOK_SECRET, the key shared with upstream systems.
+// The webhook endpoint is only served when it is set.
+func loadWebhookSecret() []byte {
+	secret := os.Getenv("WEBHOOK_SECRET")
+	if secret == "" {
+		return nil
+	}
+	log.Println("Webhook endpoint enabled")
+	return []byte(secret)
+}
+
+// isWebhookPath reports whether path is the webhook endpoint, at the top level,
+// under API_BASE_PATH or for a channel
+func isWebhookPath(path string) bool {
+	return strings.HasSuffix(path, webhookPath)
+}
+
+// WebhookHandler handles POST /api/webhook
+// The body is {"operation": "create"|"update"|"transfer", "id": "...", "data": {...}},
+// where data is the body the matching endpoint takes and id is only used by update.
+// Requests without a valid signature are rejected with 401.
+func (h *ApiHandler) WebhookHandler(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBodyBytes+1))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, fmt.Sprintf("Failed to read request body: %s", err))
+		return
+	}
+	if len(body) > maxWebhookBodyBytes {
+		writeError(w, http.StatusRequestEntityTooLarge, codeInvalidRequest, "the webhook body is too large")
+		return
+	}
+	if !h.validWebhookSignature(r.Header.Get(webhookSignatureHeader), body) {
+		writeError(w, http.StatusUnauthorized, codeUnauthorized, "Missing or invalid "+webhookSignatureHeader)
+		return
+	}
+
+	var payload struct {
+		Operation string          `json:"operation"`
+		ID        string          `json:"id"`
+		Data      json.RawMessage `json:"data"`
+	}
+	if err := decodeJSON(bytes.NewReader(body), &payload); err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, err.Error())
+		return
+	}
+	if len(payload.Data) == 0 {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "the webhook data must not be empty")
+		return
+	}
+
+	// Hand the data to the regular endpoint so it is validated exactly the same way
+	operation := r.Clone(r.Context())
+	operation.Body = io.NopCloser(bytes.NewReader(payload.Data))
+	operation.ContentLength = int64(len(payload.Data))
+
+	log.Printf("Webhook requested operation %q", payload.Operation)
+	switch payload.Operation {
+	case "create":
+		h.CreateAssetHandler(w, operation)
+	case "update":
+		h.UpdateAssetHandler(w, mux.SetURLVars(operation, map[string]string{"id": payload.ID}))
+	case "transfer":
+		h.TransferBalanceHandler(w, operation)
+	default:
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, fmt.Sprintf("unsupported webhook operation %q, expected create, update or transfer", payload.Operation))
+	}
+}
+
+// validWebhookSignature checks signature, "sha256=" followed by the hex HMAC,
+// against body in constant time
+func (h *ApiHandler) validWebhookSignature(signature string, body []byte) bool {
+	digest, ok := strings.CutPrefix(strings.TrimSpace(signature), "sha256=")
+	if !ok {
+		return false
+	}
+	got, err := hex.DecodeString(digest)
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, h.WebhookSecret)
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
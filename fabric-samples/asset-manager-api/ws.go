@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+)
+
+// Chaincode events streamed over the WebSocket. TransferBalance sets TransferEvent
+// instead of AssetEvent, so both are sent for subscribers to see every change.
+const (
+	assetEventName    = "AssetEvent"
+	transferEventName = "TransferEvent"
+)
+
+// Keepalive for idle sockets: a ping every wsPingInterval, and the socket is
+// dropped when no pong arrives within wsPongTimeout
+const (
+	wsPingInterval = 30 * time.Second
+	wsPongTimeout  = 60 * time.Second
+	wsWriteTimeout = 10 * time.Second
+)
+
+var wsUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+}
+
+// wsSubscription is a message clients send to choose which events they get.
+// An empty dealerIds list subscribes to every event again.
+type wsSubscription struct {
+	DealerIDs []string `json:"dealerIds"`
+}
+
+// wsEvent is a chaincode event as sent to WebSocket clients
+type wsEvent struct {
+	BlockNumber uint64          `json:"blockNumber"`
+	TxID        string          `json:"txId"`
+	EventName   string          `json:"eventName"`
+	Payload     json.RawMessage `json:"payload"`
+}
+
+// wsFilter holds a socket's current dealer ID filter, replaced by each subscription message
+type wsFilter struct {
+	mu        sync.Mutex
+	dealerIDs map[string]bool
+}
+
+func (f *wsFilter) set(dealerIDs []string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.dealerIDs = nil
+	if len(dealerIDs) == 0 {
+		return
+	}
+	f.dealerIDs = make(map[string]bool, len(dealerIDs))
+	for _, dealerID := range dealerIDs {
+		f.dealerIDs[normalizeDealerID(dealerID)] = true
+	}
+}
+
+// matches reports whether any of the event's dealers is subscribed to
+func (f *wsFilter) matches(dealerIDs []string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.dealerIDs == nil {
+		return true
+	}
+	for _, dealerID := range dealerIDs {
+		if f.dealerIDs[dealerID] {
+			return true
+		}
+	}
+	return false
+}
+
+// eventDealerIDs returns the dealers an AssetEvent or TransferEvent is about
+func eventDealerIDs(event *client.ChaincodeEvent) []string {
+	var payload struct {
+		DealerIDs    []string `json:"dealerIds"`
+		FromDealerID string   `json:"fromDealerId"`
+		ToDealerID   string   `json:"toDealerId"`
+	}
+	if err := json.Unmarshal(event.Payload, &payload); err != nil {
+		return nil
+	}
+	if event.EventName == transferEventName {
+		return []string{payload.FromDealerID, payload.ToDealerID}
+	}
+	return payload.DealerIDs
+}
+
+// AssetEventsSocketHandler handles GET /api/ws
+// The handshake is authenticated like any other request, then the socket receives
+// the AssetEvent and TransferEvent notifications of the request's channel as
+// {"blockNumber":N,"txId":"...","eventName":"...","payload":{...}}. Clients send
+// {"dealerIds":["D1","D2"]} to only receive events for those dealers, and the
+// first filter can be given up front with ?dealerId= as on GET /api/events.
+func (h *ApiHandler) AssetEventsSocketHandler(w http.ResponseWriter, r *http.Request) {
+	channel := requestChannel(r)
+	network := h.gateway(h.requestOrg(r)).GetNetwork(channel)
+
+	conn, err := wsUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade has already written the error response
+		log.Printf("WebSocket upgrade failed: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	// The event subscription lives as long as the socket, or until the server shuts down
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	events, err := network.ChaincodeEvents(ctx, h.Channels[channel])
+	if err != nil {
+		log.Printf("Failed to subscribe to chaincode events: %v", err)
+		conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseInternalServerErr, "failed to subscribe to chaincode events"),
+			time.Now().Add(wsWriteTimeout))
+		return
+	}
+	log.Printf("WebSocket client subscribed to events on channel %s", channel)
+
+	filter := &wsFilter{}
+	filter.set(queryDealerIDs(r))
+	go readSubscriptions(conn, filter, cancel)
+
+	ticker := time.NewTicker(wsPingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			log.Printf("WebSocket client on channel %s disconnected", channel)
+			return
+		case <-ticker.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteTimeout)); err != nil {
+				return
+			}
+		case event, ok := <-events:
+			if !ok {
+				conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseGoingAway, "event stream ended"),
+					time.Now().Add(wsWriteTimeout))
+				return
+			}
+			if event.EventName != assetEventName && event.EventName != transferEventName {
+				continue
+			}
+			if !filter.matches(eventDealerIDs(event)) {
+				continue
+			}
+
+			conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+			if err := conn.WriteJSON(wsEvent{
+				BlockNumber: event.BlockNumber,
+				TxID:        event.TransactionID,
+				EventName:   event.EventName,
+				Payload:     event.Payload,
+			}); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// readSubscriptions applies the client's subscription messages to filter until
+// the socket closes, then calls done
+func readSubscriptions(conn *websocket.Conn, filter *wsFilter, done func()) {
+	defer done()
+
+	conn.SetReadDeadline(time.Now().Add(wsPongTimeout))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(wsPongTimeout))
+	})
+
+	for {
+		var subscription wsSubscription
+		if err := conn.ReadJSON(&subscription); err != nil {
+			if _, ok := err.(*json.SyntaxError); ok {
+				continue
+			}
+			if _, ok := err.(*json.UnmarshalTypeError); ok {
+				continue
+			}
+			return
+		}
+		filter.set(subscription.DealerIDs)
+		conn.SetReadDeadline(time.Now().Add(wsPongTimeout))
+	}
+}
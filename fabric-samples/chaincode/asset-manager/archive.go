@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// archiveObjectType is the composite key namespace (archived~dealerID) for
+// the last known state of deleted assets
+const archiveObjectType = "archived"
+
+// DeleteAndArchiveAsset deletes an asset after saving its final state under
+// an archive key, so the last known values stay queryable with GetArchivedAsset
+func (s *SmartContract) DeleteAndArchiveAsset(ctx contractapi.TransactionContextInterface, dealerID string) error {
+	dealerID = normalizeDealerID(dealerID)
+	assetJSON, err := ctx.GetStub().GetState(dealerID)
+	if err != nil {
+		return fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if assetJSON == nil {
+		return fmt.Errorf("the asset %s does not exist", dealerID)
+	}
+
+	archiveKey, err := ctx.GetStub().CreateCompositeKey(archiveObjectType, []string{dealerID})
+	if err != nil {
+		return fmt.Errorf("failed to create archive key: %v", err)
+	}
+	if err := ctx.GetStub().PutState(archiveKey, assetJSON); err != nil {
+		return fmt.Errorf("failed to archive asset %s: %v", dealerID, err)
+	}
+
+	return deleteAssetState(ctx, dealerID)
+}
+
+// GetArchivedAsset returns the final state of an asset deleted with DeleteAndArchiveAsset
+func (s *SmartContract) GetArchivedAsset(ctx contractapi.TransactionContextInterface, dealerID string) (*Asset, error) {
+	dealerID = normalizeDealerID(dealerID)
+	archiveKey, err := ctx.GetStub().CreateCompositeKey(archiveObjectType, []string{dealerID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create archive key: %v", err)
+	}
+
+	assetJSON, err := ctx.GetStub().GetState(archiveKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if assetJSON == nil {
+		return nil, fmt.Errorf("the archived asset %s does not exist", dealerID)
+	}
+
+	var asset Asset
+	if err := json.Unmarshal(assetJSON, &asset); err != nil {
+		return nil, err
+	}
+
+	return &asset, nil
+}
@@ -4,11 +4,16 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
 )
 
+// chaincodeVersion is reported by GetVersion, set it at build time with
+// -ldflags "-X main.chaincodeVersion=1.2.3"
+var chaincodeVersion = "dev"
+
 // SmartContract provides functions for managing an Asset
 type SmartContract struct {
 	contractapi.Contract
@@ -17,14 +22,31 @@ type SmartContract struct {
 // Asset describes the structure of your financial accounts
 // We use json tags to control how it's serialized
 type Asset struct {
-	DEALERID    string  `json:"DEALERID"`
-	MSISDN      string  `json:"MSISDN"`
-	MPIN        string  `json:"MPIN"` // Note: Storing raw PINs is bad practice, but follows the assignment
-	BALANCE     float64 `json:"BALANCE"`
-	STATUS      string  `json:"STATUS"`
-	TRANSAMOUNT float64 `json:"TRANSAMOUNT"`
-	TRANSTYPE   string  `json:"TRANSTYPE"`
-	REMARKS     string  `json:"REMARKS"`
+	DEALERID string `json:"DEALERID"`
+	// MSISDN and MPIN are moved to privateCollection by putAsset, so they are
+	// empty in the public state except on records written by older versions
+	MSISDN      string `json:"MSISDN,omitempty"`
+	MPIN        string `json:"MPIN,omitempty"` // Salted hash of the PIN, see hashMPIN
+	BALANCE     Money  `json:"BALANCE"`
+	STATUS      string `json:"STATUS"`
+	TRANSAMOUNT Money  `json:"TRANSAMOUNT"`
+	TRANSTYPE   string `json:"TRANSTYPE"`
+	REMARKS     string `json:"REMARKS"`
+	// Currency is the ISO 4217 code BALANCE and TRANSAMOUNT are in. It is empty
+	// on assets created before it existed.
+	Currency string `json:"Currency,omitempty"`
+
+	// LastTransferTxID links both legs of the most recent TransferBalance
+	LastTransferTxID string `json:"LastTransferTxID,omitempty"`
+	// CreatedAt is the UTC timestamp of the creating transaction, in createdAtLayout
+	CreatedAt string `json:"CreatedAt,omitempty"`
+	// LastModifiedBy is the client identity ID of the last transaction that wrote the asset
+	LastModifiedBy string `json:"LastModifiedBy,omitempty"`
+	// Metadata holds deployment specific attributes, within the limits of validateMetadata
+	Metadata map[string]string `json:"Metadata,omitempty"`
+	// SchemaVersion is the layout the asset was stored with, see currentSchemaVersion.
+	// It is absent on records written before it existed.
+	SchemaVersion int `json:"SchemaVersion,omitempty"`
 }
 
 // HistoryQueryResult structure used for returning history query results
@@ -33,13 +55,61 @@ type HistoryQueryResult struct {
 	TxId      string    `json:"txId"`
 	Timestamp time.Time `json:"timestamp"`
 	IsDelete  bool      `json:"isDelete"`
+	// IsCreate marks the earliest record of the key, the one that created the asset.
+	// It is set by GetAssetHistory and GetAssetHistoryWithChanges, unless the
+	// history was truncated.
+	IsCreate bool `json:"isCreate"`
+
+	// Changes is only filled in by GetAssetHistoryWithChanges
+	Changes map[string]FieldChange `json:"changes,omitempty"`
+}
+
+// FieldChange holds the previous and new value of a field that changed
+// between two consecutive history records
+type FieldChange struct {
+	Old string `json:"old"`
+	New string `json:"new"`
 }
 
 // CreateAsset issues a new asset to the world state.
-// The DEALERID will be used as the key.
+// The DEALERID will be used as the key. BALANCE and TRANSAMOUNT are
+// decimal strings with at most two decimal places. The MPIN is read from
+// the transient map, along with the random salt under SALT, and only its scrypt
+// hash is stored. metadataJSON is a JSON object
+// of strings, or empty for no metadata. currency is the ISO 4217 code of the
+// amounts and must be one of the allowed currencies. status can't be FROZEN,
+// only FreezeAsset freezes an asset.
 func (s *SmartContract) CreateAsset(ctx contractapi.TransactionContextInterface,
-	dealerID string, msisdn string, mpin string, balance float64, status string,
-	transAmount float64, transType string, remarks string) error {
+	dealerID string, msisdn string, balance string, status string,
+	transAmount string, transType string, remarks string, metadataJSON string, currency string) error {
+
+	dealerID = normalizeDealerID(dealerID)
+	if err := validateDealerID(dealerID); err != nil {
+		return err
+	}
+	if err := validateMSISDN(dealerID, msisdn); err != nil {
+		return err
+	}
+	balanceValue, err := newMoney("BALANCE", balance)
+	if err != nil {
+		return err
+	}
+	transAmountValue, err := newMoney("TRANSAMOUNT", transAmount)
+	if err != nil {
+		return err
+	}
+	metadata, _, err := parseMetadata(metadataJSON)
+	if err != nil {
+		return err
+	}
+	mpin, _, err := transientMPIN(ctx)
+	if err != nil {
+		return err
+	}
+	storedMPIN, err := hashMPIN(ctx, mpin)
+	if err != nil {
+		return err
+	}
 
 	exists, err := s.AssetExists(ctx, dealerID)
 	if err != nil {
@@ -49,26 +119,60 @@ func (s *SmartContract) CreateAsset(ctx contractapi.TransactionContextInterface,
 		return fmt.Errorf("the asset %s already exists", dealerID)
 	}
 
+	createdAt, err := txTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+
+	config, err := getConfig(ctx)
+	if err != nil {
+		return err
+	}
+	currency = normalizeCurrency(currency)
+	if currency == "" {
+		return fmt.Errorf("invalid asset %s: Currency must not be empty", dealerID)
+	}
+	if err := validateCurrency(currency, config.AllowedCurrencies); err != nil {
+		return fmt.Errorf("invalid asset %s: %v", dealerID, err)
+	}
+
+	// Fill in the configured defaults rather than storing empty strings
+	if strings.TrimSpace(status) == "" {
+		status = config.DefaultStatus
+	}
+	if strings.TrimSpace(remarks) == "" {
+		remarks = config.DefaultRemarks
+	}
+
 	asset := Asset{
 		DEALERID:    dealerID,
 		MSISDN:      msisdn,
-		MPIN:        mpin,
-		BALANCE:     balance,
+		MPIN:        storedMPIN,
+		BALANCE:     balanceValue,
 		STATUS:      status,
-		TRANSAMOUNT: transAmount,
+		TRANSAMOUNT: transAmountValue,
 		TRANSTYPE:   transType,
 		REMARKS:     remarks,
+		Currency:    currency,
+		Metadata:    metadata,
+
+		CreatedAt: createdAt.Format(createdAtLayout),
 	}
-	assetJSON, err := json.Marshal(asset)
-	if err != nil {
+	if err := validateAsset(&asset); err != nil {
 		return err
 	}
-
-	return ctx.GetStub().PutState(dealerID, assetJSON)
+	if asset.STATUS == statusFrozen {
+		return fmt.Errorf("the asset %s cannot be created FROZEN, use FreezeAsset once it exists", dealerID)
+	}
+	if err := checkConfiguredLimits(ctx, &asset); err != nil {
+		return err
+	}
+	return putAsset(ctx, &asset)
 }
 
 // ReadAsset returns the asset stored in the world state with given id
 func (s *SmartContract) ReadAsset(ctx contractapi.TransactionContextInterface, dealerID string) (*Asset, error) {
+	dealerID = normalizeDealerID(dealerID)
 	assetJSON, err := ctx.GetStub().GetState(dealerID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read from world state: %v", err)
@@ -87,41 +191,158 @@ func (s *SmartContract) ReadAsset(ctx contractapi.TransactionContextInterface, d
 }
 
 // UpdateAsset updates an existing asset in the world state
-// This is a simple implementation that overwrites the entire asset.
-// A real-world app might only update specific fields (e.g., BALANCE).
+// This is a simple implementation that overwrites the entire asset,
+// except for the MPIN, which is kept unless the transient map has a new one,
+// the Metadata, which is kept when metadataJSON is empty, and the Currency, which
+// is kept when currency is empty and can't change once set.
+// BALANCE can only change through Deposit, Withdraw or TransferBalance,
+// unless an admin has enabled AllowDirectBalanceUpdates in the config.
+// STATUS can't be set to or from FROZEN, that's FreezeAsset and UnfreezeAsset.
 func (s *SmartContract) UpdateAsset(ctx contractapi.TransactionContextInterface,
-	dealerID string, msisdn string, mpin string, balance float64, status string,
-	transAmount float64, transType string, remarks string) error {
+	dealerID string, msisdn string, balance string, status string,
+	transAmount string, transType string, remarks string, metadataJSON string, currency string) error {
 
-	exists, err := s.AssetExists(ctx, dealerID)
+	dealerID = normalizeDealerID(dealerID)
+	if err := validateDealerID(dealerID); err != nil {
+		return err
+	}
+	balanceValue, err := newMoney("BALANCE", balance)
 	if err != nil {
 		return err
 	}
-	if !exists {
-		return fmt.Errorf("the asset %s does not exist", dealerID)
+	transAmountValue, err := newMoney("TRANSAMOUNT", transAmount)
+	if err != nil {
+		return err
+	}
+
+	if err := validateMSISDN(dealerID, msisdn); err != nil {
+		return err
+	}
+	metadata, metadataGiven, err := parseMetadata(metadataJSON)
+	if err != nil {
+		return err
+	}
+
+	existing, err := s.ReadAsset(ctx, dealerID)
+	if err != nil {
+		return err
+	}
+	// An empty metadataJSON keeps the stored metadata, "{}" clears it
+	if !metadataGiven {
+		metadata = existing.Metadata
+	}
+	currency = normalizeCurrency(currency)
+	if currency == "" {
+		currency = existing.Currency
+	}
+
+	// The MPIN only changes when a new one is sent in the transient map.
+	// Otherwise the private MPIN is kept, and a legacy public one is moved over.
+	storedMPIN := existing.MPIN
+	if mpin, ok, err := transientMPIN(ctx); err != nil {
+		return err
+	} else if ok {
+		if storedMPIN, err = hashMPIN(ctx, mpin); err != nil {
+			return err
+		}
 	}
 
 	// Overwriting original asset with new asset
 	asset := Asset{
 		DEALERID:    dealerID,
 		MSISDN:      msisdn,
-		MPIN:        mpin,
-		BALANCE:     balance,
+		MPIN:        storedMPIN,
+		BALANCE:     balanceValue,
 		STATUS:      status,
-		TRANSAMOUNT: transAmount,
+		TRANSAMOUNT: transAmountValue,
 		TRANSTYPE:   transType,
 		REMARKS:     remarks,
+		Currency:    currency,
+		Metadata:    metadata,
+
+		LastTransferTxID: existing.LastTransferTxID,
+		CreatedAt:        existing.CreatedAt,
 	}
-	assetJSON, err := json.Marshal(asset)
-	if err != nil {
+	if err := validateAsset(&asset); err != nil {
 		return err
 	}
+	if err := checkConfiguredLimits(ctx, &asset); err != nil {
+		return err
+	}
+	if err := checkAssetUpdate(ctx, existing, &asset); err != nil {
+		return err
+	}
+
+	return putAsset(ctx, &asset)
+}
+
+// UpsertAsset creates the asset if it doesn't exist and updates it otherwise,
+// with the same arguments and rules as CreateAsset and UpdateAsset. It returns
+// true when the asset was created.
+func (s *SmartContract) UpsertAsset(ctx contractapi.TransactionContextInterface,
+	dealerID string, msisdn string, balance string, status string,
+	transAmount string, transType string, remarks string, metadataJSON string, currency string) (bool, error) {
+
+	exists, err := s.AssetExists(ctx, dealerID)
+	if err != nil {
+		return false, err
+	}
+	if exists {
+		return false, s.UpdateAsset(ctx, dealerID, msisdn, balance, status, transAmount, transType, remarks, metadataJSON, currency)
+	}
+	if err := s.CreateAsset(ctx, dealerID, msisdn, balance, status, transAmount, transType, remarks, metadataJSON, currency); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// checkAssetUpdate enforces the rules shared by UpdateAsset and UpdateAssetFields
+// on what a plain update may change
+func checkAssetUpdate(ctx contractapi.TransactionContextInterface, existing *Asset, updated *Asset) error {
+	// Only FreezeAsset may set a freeze and only UnfreezeAsset may lift it
+	if existing.STATUS == statusFrozen && updated.STATUS != statusFrozen {
+		return fmt.Errorf("the asset %s is frozen, use UnfreezeAsset to change its STATUS", existing.DEALERID)
+	}
+	if updated.STATUS == statusFrozen && existing.STATUS != statusFrozen {
+		return fmt.Errorf("use FreezeAsset to freeze asset %s", existing.DEALERID)
+	}
 
-	return ctx.GetStub().PutState(dealerID, assetJSON)
+	// Assets from before Currency existed may have it set once, then it's fixed
+	// as BALANCE is held in it
+	if updated.Currency != existing.Currency {
+		if existing.Currency != "" {
+			return fmt.Errorf("the Currency of asset %s cannot change from %s to %s", existing.DEALERID, existing.Currency, updated.Currency)
+		}
+		config, err := getConfig(ctx)
+		if err != nil {
+			return err
+		}
+		if err := validateCurrency(updated.Currency, config.AllowedCurrencies); err != nil {
+			return fmt.Errorf("invalid asset %s: %v", existing.DEALERID, err)
+		}
+	}
+
+	if updated.BALANCE != existing.BALANCE {
+		config, err := getConfig(ctx)
+		if err != nil {
+			return err
+		}
+		if !config.AllowDirectBalanceUpdates {
+			return fmt.Errorf("the BALANCE of asset %s cannot be set directly, use Deposit, Withdraw or TransferBalance", existing.DEALERID)
+		}
+	}
+
+	// Resending the stored transaction metadata is fine, recording a new one
+	// must match the balance change
+	if updated.TRANSTYPE != existing.TRANSTYPE || updated.TRANSAMOUNT != existing.TRANSAMOUNT {
+		return validateTransaction(existing.BALANCE, updated)
+	}
+	return nil
 }
 
 // DeleteAsset deletes an given asset from the world state using its dealerID.
 func (s *SmartContract) DeleteAsset(ctx contractapi.TransactionContextInterface, dealerID string) error {
+	dealerID = normalizeDealerID(dealerID)
 	// First, check if the asset exists using the dealerID
 	exists, err := s.AssetExists(ctx, dealerID)
 	if err != nil {
@@ -134,16 +355,40 @@ func (s *SmartContract) DeleteAsset(ctx contractapi.TransactionContextInterface,
 	}
 
 	// If it exists, delete it from the world state using the dealerID as the key
-	err = ctx.GetStub().DelState(dealerID)
+	err = deleteAssetState(ctx, dealerID)
 	if err != nil {
 		// Return an error if deletion failed
-		return fmt.Errorf("failed to delete asset %s: %v", dealerID, err)
+		return err
 	}
 
 	// Return nil on success
 	return nil
 }
 
+// DeleteAssetIfBalance deletes an asset like DeleteAsset, but only when its stored
+// BALANCE equals expectedBalance, a decimal string, as a check that the caller
+// is deleting the account it thinks it is
+func (s *SmartContract) DeleteAssetIfBalance(ctx contractapi.TransactionContextInterface, dealerID string, expectedBalance string) error {
+	dealerID = normalizeDealerID(dealerID)
+	expected, err := newMoney("expected BALANCE", expectedBalance)
+	if err != nil {
+		return err
+	}
+
+	asset, err := readStoredAsset(ctx, dealerID)
+	if err != nil {
+		return err
+	}
+	if asset == nil {
+		return fmt.Errorf("the asset %s does not exist", dealerID)
+	}
+	if asset.BALANCE.cents() != expected.cents() {
+		return fmt.Errorf("the BALANCE of asset %s is %s, which does not match the expected balance %s", dealerID, asset.BALANCE, expected)
+	}
+
+	return deleteAssetState(ctx, dealerID)
+}
+
 // GetAllAssets returns all assets found in the world state.
 func (s *SmartContract) GetAllAssets(ctx contractapi.TransactionContextInterface) ([]*Asset, error) {
 	// Use GetStateByRange with empty start and end keys to get all assets
@@ -185,6 +430,7 @@ func (s *SmartContract) GetAllAssets(ctx contractapi.TransactionContextInterface
 
 // AssetExists returns true when asset with given ID exists in world state
 func (s *SmartContract) AssetExists(ctx contractapi.TransactionContextInterface, dealerID string) (bool, error) {
+	dealerID = normalizeDealerID(dealerID)
 	assetJSON, err := ctx.GetStub().GetState(dealerID)
 	if err != nil {
 		return false, fmt.Errorf("failed to read from world state: %v", err)
@@ -193,44 +439,91 @@ func (s *SmartContract) AssetExists(ctx contractapi.TransactionContextInterface,
 	return assetJSON != nil, nil
 }
 
-// GetAssetHistory returns the chain of custody for an asset
+// GetAssetHistory returns the chain of custody for an asset, at most
+// MaxHistoryRecords records of it. GetBoundedAssetHistory also says whether
+// records were left out.
 func (s *SmartContract) GetAssetHistory(ctx contractapi.TransactionContextInterface, dealerID string) ([]HistoryQueryResult, error) {
-	log.Printf("GetAssetHistory: ID %s", dealerID)
+	dealerID = normalizeDealerID(dealerID)
+	logf(levelInfo, "GetAssetHistory: ID %s", dealerID)
 
-	resultsIterator, err := ctx.GetStub().GetHistoryForKey(dealerID)
+	config, err := getConfig(ctx)
 	if err != nil {
 		return nil, err
 	}
+	records, _, err := readAssetHistory(ctx, dealerID, config.MaxHistoryRecords)
+	return records, err
+}
+
+// readAssetHistory reads at most maxRecords records of the asset's history,
+// reporting whether the key has more records than that
+func readAssetHistory(ctx contractapi.TransactionContextInterface, dealerID string, maxRecords int) ([]HistoryQueryResult, bool, error) {
+	resultsIterator, err := ctx.GetStub().GetHistoryForKey(dealerID)
+	if err != nil {
+		return nil, false, err
+	}
 	defer resultsIterator.Close()
 
 	var records []HistoryQueryResult
-	for resultsIterator.HasNext() {
+	earliest := -1
+	for len(records) < maxRecords && resultsIterator.HasNext() {
 		response, err := resultsIterator.Next()
 		if err != nil {
-			return nil, err
+			return nil, false, err
 		}
 
-		var asset Asset
-		if len(response.Value) > 0 {
-			err = json.Unmarshal(response.Value, &asset)
-			if err != nil {
-				return nil, err
-			}
-		} else {
-			asset = Asset{
-				DEALERID: dealerID,
-			}
+		record, err := newHistoryQueryResult(dealerID, response)
+		if err != nil {
+			return nil, false, err
 		}
 
-		records = append(records, HistoryQueryResult{
-			TxId:      response.TxId,
-			Timestamp: response.Timestamp.AsTime(),
-			Record:    &asset,
-			IsDelete:  response.IsDelete,
-		})
+		// Don't rely on the iterator order, compare timestamps instead
+		if earliest < 0 || record.Timestamp.Before(records[earliest].Timestamp) {
+			earliest = len(records)
+		}
+		records = append(records, *record)
+	}
+
+	// When records were left out the creating record may be one of them
+	truncated := resultsIterator.HasNext()
+	if truncated {
+		logf(levelInfo, "History of %s truncated to %d records", dealerID, len(records))
+	} else if earliest >= 0 {
+		records[earliest].IsCreate = true
 	}
+	return records, truncated, nil
+}
+
+// GetVersion returns the version this chaincode was built with
+func (s *SmartContract) GetVersion(ctx contractapi.TransactionContextInterface) string {
+	return chaincodeVersion
+}
+
+// createdAtLayout is a fixed width RFC3339 layout, so CreatedAt values
+// compare chronologically as plain strings in CouchDB selectors
+const createdAtLayout = "2006-01-02T15:04:05Z"
 
-	return records, nil
+// txTimestamp returns the transaction timestamp in UTC. Unlike time.Now it is
+// the same on every endorsing peer.
+func txTimestamp(ctx contractapi.TransactionContextInterface) (time.Time, error) {
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	return timestamp.AsTime().UTC(), nil
+}
+
+// normalizeDealerID applies the dealer ID policy: surrounding whitespace is
+// trimmed and letters are uppercased, so " d123 " and "D123" are the same key
+func normalizeDealerID(dealerID string) string {
+	return strings.ToUpper(strings.TrimSpace(dealerID))
+}
+
+// validateDealerID rejects dealer IDs that would produce an empty world state key
+func validateDealerID(dealerID string) error {
+	if dealerID == "" {
+		return fmt.Errorf("the dealer ID must not be empty (dealer IDs are trimmed and uppercased)")
+	}
+	return nil
 }
 
 func main() {
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// isAdmin reports whether the submitting identity is an admin, either through
+// the "admin" node OU (e.g. Admin@org1.example.com) or a role=admin certificate attribute
+func isAdmin(ctx contractapi.TransactionContextInterface) (bool, error) {
+	identity := ctx.GetClientIdentity()
+
+	role, found, err := identity.GetAttributeValue("role")
+	if err != nil {
+		return false, fmt.Errorf("failed to read client identity attributes: %v", err)
+	}
+	if found && role == "admin" {
+		return true, nil
+	}
+
+	cert, err := identity.GetX509Certificate()
+	if err != nil {
+		return false, fmt.Errorf("failed to read client certificate: %v", err)
+	}
+	for _, ou := range cert.Subject.OrganizationalUnit {
+		if ou == "admin" {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// requireAdmin returns an error unless the submitting identity is an admin
+func requireAdmin(ctx contractapi.TransactionContextInterface) error {
+	admin, err := isAdmin(ctx)
+	if err != nil {
+		return err
+	}
+	if !admin {
+		return fmt.Errorf("this operation requires an admin identity")
+	}
+	return nil
+}
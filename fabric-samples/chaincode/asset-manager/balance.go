@@ -0,0 +1,318 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// TRANSTYPE values whose TRANSAMOUNT must match the change in BALANCE
+const (
+	transTypeDebit  = "DEBIT"
+	transTypeCredit = "CREDIT"
+)
+
+// TransferEvent is emitted once per TransferBalance or TransferBalanceWithRate so downstream systems
+// can match the debit and credit legs of the same transfer.
+type TransferEvent struct {
+	TxID         string `json:"txId"`
+	FromDealerID string `json:"fromDealerId"`
+	ToDealerID   string `json:"toDealerId"`
+	Amount       Money  `json:"amount"`
+
+	// Set on transfers between currencies: the amount credited in the
+	// recipient's currency and the rate used
+	FromCurrency   string `json:"fromCurrency,omitempty"`
+	ToCurrency     string `json:"toCurrency,omitempty"`
+	ToAmount       Money  `json:"toAmount,omitempty"`
+	ConversionRate string `json:"conversionRate,omitempty"`
+}
+
+// TransferBalance moves amount, a decimal string, from one dealer to another in a
+// single transaction. Both assets record the transaction ID in LastTransferTxID
+// for reconciliation. The assets must hold the same currency, use
+// TransferBalanceWithRate between currencies.
+func (s *SmartContract) TransferBalance(ctx contractapi.TransactionContextInterface,
+	fromDealerID string, toDealerID string, amount string) error {
+
+	return s.transferBalance(ctx, fromDealerID, toDealerID, amount, "")
+}
+
+// TransferBalanceWithRate is TransferBalance between assets in different
+// currencies. conversionRate is the recipient's units per unit of the sender's:
+// amount is debited and amount times the rate, rounded half up, is credited.
+func (s *SmartContract) TransferBalanceWithRate(ctx contractapi.TransactionContextInterface,
+	fromDealerID string, toDealerID string, amount string, conversionRate string) error {
+
+	if strings.TrimSpace(conversionRate) == "" {
+		return fmt.Errorf("the conversion rate must not be empty, use TransferBalance within a currency")
+	}
+	return s.transferBalance(ctx, fromDealerID, toDealerID, amount, conversionRate)
+}
+
+// transferBalance implements TransferBalance and TransferBalanceWithRate, an
+// empty conversionRate means a transfer within a currency
+func (s *SmartContract) transferBalance(ctx contractapi.TransactionContextInterface,
+	fromDealerID string, toDealerID string, amount string, conversionRate string) error {
+
+	fromDealerID = normalizeDealerID(fromDealerID)
+	toDealerID = normalizeDealerID(toDealerID)
+
+	cents, err := parseMoney(amount)
+	if err != nil {
+		return fmt.Errorf("invalid transfer amount: %v", err)
+	}
+	if cents <= 0 {
+		return fmt.Errorf("transfer amount must be positive, got %s", amount)
+	}
+	if fromDealerID == toDealerID {
+		return fmt.Errorf("cannot transfer from asset %s to itself", fromDealerID)
+	}
+
+	from, err := s.ReadAsset(ctx, fromDealerID)
+	if err != nil {
+		return err
+	}
+	to, err := s.ReadAsset(ctx, toDealerID)
+	if err != nil {
+		return err
+	}
+	if err := requireNotFrozen(from); err != nil {
+		return err
+	}
+	if err := requireNotFrozen(to); err != nil {
+		return err
+	}
+
+	toCents, err := transferCredit(from, to, cents, conversionRate)
+	if err != nil {
+		return err
+	}
+
+	txID := ctx.GetStub().GetTxID()
+	fromBalance, toBalance := from.BALANCE, to.BALANCE
+
+	from.BALANCE = moneyFromCents(fromBalance.cents() - cents)
+	from.TRANSAMOUNT = moneyFromCents(cents)
+	from.TRANSTYPE = transTypeDebit
+	from.LastTransferTxID = txID
+
+	to.BALANCE = moneyFromCents(toBalance.cents() + toCents)
+	to.TRANSAMOUNT = moneyFromCents(toCents)
+	to.TRANSTYPE = transTypeCredit
+	to.LastTransferTxID = txID
+
+	if err := checkConfiguredLimits(ctx, from); err != nil {
+		return err
+	}
+	if err := checkConfiguredLimits(ctx, to); err != nil {
+		return err
+	}
+
+	if err := putAsset(ctx, from); err != nil {
+		return err
+	}
+	if err := putAsset(ctx, to); err != nil {
+		return err
+	}
+
+	event := TransferEvent{
+		TxID:         txID,
+		FromDealerID: fromDealerID,
+		ToDealerID:   toDealerID,
+		Amount:       moneyFromCents(cents),
+	}
+	if !sameCurrency(from, to) {
+		event.FromCurrency = from.Currency
+		event.ToCurrency = to.Currency
+		event.ToAmount = moneyFromCents(toCents)
+		event.ConversionRate = strings.TrimSpace(conversionRate)
+	}
+	eventJSON, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	return ctx.GetStub().SetEvent("TransferEvent", eventJSON)
+}
+
+// sameCurrency reports whether a transfer between the assets stays within one
+// currency. A missing Currency predates the field and counts as the other asset's.
+func sameCurrency(from *Asset, to *Asset) bool {
+	return from.Currency == "" || to.Currency == "" || from.Currency == to.Currency
+}
+
+// transferCredit returns what a transfer of cents from one asset credits to the
+// other: the same amount within a currency, or the amount converted with rate
+// between currencies
+func transferCredit(from *Asset, to *Asset, cents int64, rate string) (int64, error) {
+	if strings.TrimSpace(rate) == "" {
+		if !sameCurrency(from, to) {
+			return 0, fmt.Errorf("cannot transfer from asset %s in %s to asset %s in %s without a conversion rate",
+				from.DEALERID, from.Currency, to.DEALERID, to.Currency)
+		}
+		return cents, nil
+	}
+	if sameCurrency(from, to) {
+		return 0, fmt.Errorf("a conversion rate is only accepted between assets with different currencies")
+	}
+
+	toCents, err := convertAmount(cents, rate, to.Currency)
+	if err != nil {
+		return 0, err
+	}
+	if toCents <= 0 {
+		return 0, fmt.Errorf("transfer amount %s converts to nothing in %s at rate %s", moneyFromCents(cents), to.Currency, rate)
+	}
+	return toCents, nil
+}
+
+// Deposit adds amount, a decimal string, to the asset's BALANCE and records it as a CREDIT
+func (s *SmartContract) Deposit(ctx contractapi.TransactionContextInterface, dealerID string, amount string) error {
+	cents, err := parseMoney(amount)
+	if err != nil {
+		return fmt.Errorf("invalid deposit amount: %v", err)
+	}
+	if cents <= 0 {
+		return fmt.Errorf("deposit amount must be positive, got %s", amount)
+	}
+
+	_, err = s.postTransaction(ctx, dealerID, cents, transTypeCredit, nil)
+	return err
+}
+
+// Withdraw subtracts amount, a decimal string, from the asset's BALANCE and records it as a DEBIT
+func (s *SmartContract) Withdraw(ctx contractapi.TransactionContextInterface, dealerID string, amount string) error {
+	cents, err := parseMoney(amount)
+	if err != nil {
+		return fmt.Errorf("invalid withdrawal amount: %v", err)
+	}
+	if cents <= 0 {
+		return fmt.Errorf("withdrawal amount must be positive, got %s", amount)
+	}
+
+	_, err = s.postTransaction(ctx, dealerID, cents, transTypeDebit, nil)
+	return err
+}
+
+// PostTransaction records a CREDIT or DEBIT of amount, a decimal string, on the
+// asset: BALANCE moves by the amount and TRANSAMOUNT, TRANSTYPE and REMARKS describe
+// the transaction. It returns the updated asset. The transaction's time is its
+// timestamp in the asset history; CreatedAt stays the asset's creation time.
+func (s *SmartContract) PostTransaction(ctx contractapi.TransactionContextInterface,
+	dealerID string, amount string, transType string, remarks string) (*Asset, error) {
+
+	cents, err := parseMoney(amount)
+	if err != nil {
+		return nil, fmt.Errorf("invalid transaction amount: %v", err)
+	}
+	if cents <= 0 {
+		return nil, fmt.Errorf("transaction amount must be positive, got %s", amount)
+	}
+	transType = strings.ToUpper(strings.TrimSpace(transType))
+	if transType != transTypeCredit && transType != transTypeDebit {
+		return nil, fmt.Errorf("TRANSTYPE must be %s or %s, got %q", transTypeCredit, transTypeDebit, transType)
+	}
+
+	return s.postTransaction(ctx, dealerID, cents, transType, &remarks)
+}
+
+// postTransaction applies a CREDIT or DEBIT of cents to the asset and stores it.
+// REMARKS are only replaced when remarks isn't nil.
+func (s *SmartContract) postTransaction(ctx contractapi.TransactionContextInterface,
+	dealerID string, cents int64, transType string, remarks *string) (*Asset, error) {
+
+	dealerID = normalizeDealerID(dealerID)
+	asset, err := s.ReadAsset(ctx, dealerID)
+	if err != nil {
+		return nil, err
+	}
+	if err := requireNotFrozen(asset); err != nil {
+		return nil, err
+	}
+
+	previousBalance := asset.BALANCE
+	if transType == transTypeDebit {
+		asset.BALANCE = moneyFromCents(previousBalance.cents() - cents)
+	} else {
+		asset.BALANCE = moneyFromCents(previousBalance.cents() + cents)
+	}
+	asset.TRANSAMOUNT = moneyFromCents(cents)
+	asset.TRANSTYPE = transType
+	if remarks != nil {
+		asset.REMARKS = *remarks
+	}
+	if err := checkConfiguredLimits(ctx, asset); err != nil {
+		return nil, err
+	}
+
+	if err := putAsset(ctx, asset); err != nil {
+		return nil, err
+	}
+	return asset, nil
+}
+
+// validateTransaction checks that a DEBIT or CREDIT recorded on the asset matches
+// the change from previousBalance, so the transaction metadata can't contradict the
+// balance. Other TRANSTYPE values carry no balance semantics and are not checked.
+// Only UpdateAsset needs it, where the client sends both; the balance
+// transactions derive BALANCE and TRANSAMOUNT from the same amount.
+func validateTransaction(previousBalance Money, asset *Asset) error {
+	var expected int64
+	switch asset.TRANSTYPE {
+	case transTypeDebit:
+		expected = previousBalance.cents() - asset.TRANSAMOUNT.cents()
+	case transTypeCredit:
+		expected = previousBalance.cents() + asset.TRANSAMOUNT.cents()
+	default:
+		return nil
+	}
+
+	if asset.TRANSAMOUNT.cents() < 0 {
+		return fmt.Errorf("the TRANSAMOUNT of a %s must not be negative, got %s", asset.TRANSTYPE, asset.TRANSAMOUNT)
+	}
+	if asset.BALANCE.cents() != expected {
+		return fmt.Errorf("a %s of %s on asset %s must change BALANCE from %s to %s, got %s",
+			asset.TRANSTYPE, asset.TRANSAMOUNT, asset.DEALERID, previousBalance, moneyFromCents(expected), asset.BALANCE)
+	}
+	return nil
+}
+
+// putAsset marshals an asset and writes it to the world state under its DEALERID,
+// keeping the secondary indexes in step and setting the AssetEvent
+func putAsset(ctx contractapi.TransactionContextInterface, asset *Asset) error {
+	modifiedBy, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client identity: %v", err)
+	}
+	asset.LastModifiedBy = modifiedBy
+	asset.SchemaVersion = currentSchemaVersion
+	if err := movePrivateFields(ctx, asset); err != nil {
+		return err
+	}
+
+	previous, err := readStoredAsset(ctx, asset.DEALERID)
+	if err != nil {
+		return err
+	}
+
+	assetJSON, err := json.Marshal(asset)
+	if err != nil {
+		return err
+	}
+
+	if err := ctx.GetStub().PutState(asset.DEALERID, assetJSON); err != nil {
+		return err
+	}
+	if err := updateIndexes(ctx, previous, asset); err != nil {
+		return err
+	}
+
+	action := assetActionUpdate
+	if previous == nil {
+		action = assetActionCreate
+	}
+	return setAssetEvent(ctx, action, asset.DEALERID)
+}
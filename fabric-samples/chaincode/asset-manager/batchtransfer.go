@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// maxDisbursements bounds how many recipients one BatchTransfer can pay
+const maxDisbursements = 100
+
+// Disbursement is one recipient of a BatchTransfer
+type Disbursement struct {
+	ToDealerID string `json:"toDealerId"`
+	Amount     string `json:"amount"` // Decimal string, e.g. "100.50"
+}
+
+// DisbursementResult is a recipient's side of a committed BatchTransfer
+type DisbursementResult struct {
+	ToDealerID string `json:"toDealerId"`
+	Amount     Money  `json:"amount"`
+	Balance    Money  `json:"balance"` // The recipient's BALANCE after the credit
+}
+
+// BatchTransferResult reports what BatchTransfer did
+type BatchTransferResult struct {
+	TxID         string               `json:"txId"`
+	FromDealerID string               `json:"fromDealerId"`
+	Total        Money                `json:"total"`
+	FromBalance  Money                `json:"fromBalance"` // The source's BALANCE after the debit
+	Results      []DisbursementResult `json:"results"`
+}
+
+// BatchTransfer pays every disbursement in disbursementsJSON, a JSON array of
+// {"toDealerId":"...","amount":"..."}, from one source asset in a single
+// transaction. The source is debited once with the total and each recipient is
+// credited its amount. Every asset records the transaction ID in
+// LastTransferTxID. Recipients must hold the source's currency. If any recipient
+// is invalid, the source can't cover the total or a limit is exceeded, the whole
+// transaction fails and nothing is paid.
+func (s *SmartContract) BatchTransfer(ctx contractapi.TransactionContextInterface,
+	fromDealerID string, disbursementsJSON string) (*BatchTransferResult, error) {
+
+	fromDealerID = normalizeDealerID(fromDealerID)
+
+	var disbursements []Disbursement
+	if err := json.Unmarshal([]byte(disbursementsJSON), &disbursements); err != nil {
+		return nil, fmt.Errorf("the disbursements must be a JSON array of {\"toDealerId\",\"amount\"} objects: %v", err)
+	}
+	if len(disbursements) == 0 {
+		return nil, fmt.Errorf("at least one disbursement is required")
+	}
+	if len(disbursements) > maxDisbursements {
+		return nil, fmt.Errorf("at most %d disbursements are allowed, got %d", maxDisbursements, len(disbursements))
+	}
+
+	from, err := s.ReadAsset(ctx, fromDealerID)
+	if err != nil {
+		return nil, err
+	}
+	if err := requireNotFrozen(from); err != nil {
+		return nil, err
+	}
+
+	// Validate every recipient before writing anything. Writes in a transaction
+	// aren't visible to its own reads, so a recipient may only appear once.
+	txID := ctx.GetStub().GetTxID()
+	recipients := make([]*Asset, len(disbursements))
+	amounts := make([]int64, len(disbursements))
+	seen := make(map[string]bool, len(disbursements))
+	var total int64
+	for i, disbursement := range disbursements {
+		toDealerID := normalizeDealerID(disbursement.ToDealerID)
+		if toDealerID == "" {
+			return nil, fmt.Errorf("disbursement %d: the toDealerId must not be empty", i)
+		}
+		if toDealerID == fromDealerID {
+			return nil, fmt.Errorf("disbursement %d: cannot transfer from asset %s to itself", i, fromDealerID)
+		}
+		if seen[toDealerID] {
+			return nil, fmt.Errorf("disbursement %d: asset %s appears more than once", i, toDealerID)
+		}
+		seen[toDealerID] = true
+
+		cents, err := parseMoney(disbursement.Amount)
+		if err != nil {
+			return nil, fmt.Errorf("disbursement %d: invalid transfer amount: %v", i, err)
+		}
+		if cents <= 0 {
+			return nil, fmt.Errorf("disbursement %d: transfer amount must be positive, got %s", i, disbursement.Amount)
+		}
+
+		to, err := s.ReadAsset(ctx, toDealerID)
+		if err != nil {
+			return nil, fmt.Errorf("disbursement %d: %v", i, err)
+		}
+		if err := requireNotFrozen(to); err != nil {
+			return nil, fmt.Errorf("disbursement %d: %v", i, err)
+		}
+		if _, err := transferCredit(from, to, cents, ""); err != nil {
+			return nil, fmt.Errorf("disbursement %d: %v, use TransferBalanceWithRate for transfers between currencies", i, err)
+		}
+
+		toBalance := to.BALANCE
+		to.BALANCE = moneyFromCents(toBalance.cents() + cents)
+		to.TRANSAMOUNT = moneyFromCents(cents)
+		to.TRANSTYPE = transTypeCredit
+		to.LastTransferTxID = txID
+		if err := checkConfiguredLimits(ctx, to); err != nil {
+			return nil, fmt.Errorf("disbursement %d: %v", i, err)
+		}
+
+		recipients[i] = to
+		amounts[i] = cents
+		total += cents
+	}
+
+	// The source's balance floor is checked against the whole batch
+	fromBalance := from.BALANCE
+	from.BALANCE = moneyFromCents(fromBalance.cents() - total)
+	from.TRANSAMOUNT = moneyFromCents(total)
+	from.TRANSTYPE = transTypeDebit
+	from.LastTransferTxID = txID
+	if err := checkConfiguredLimits(ctx, from); err != nil {
+		return nil, err
+	}
+
+	if err := putAsset(ctx, from); err != nil {
+		return nil, err
+	}
+	result := &BatchTransferResult{
+		TxID:         txID,
+		FromDealerID: fromDealerID,
+		Total:        moneyFromCents(total),
+		FromBalance:  from.BALANCE,
+		Results:      make([]DisbursementResult, 0, len(recipients)),
+	}
+	dealerIDs := []string{fromDealerID}
+	for i, to := range recipients {
+		if err := putAsset(ctx, to); err != nil {
+			return nil, err
+		}
+		result.Results = append(result.Results, DisbursementResult{
+			ToDealerID: to.DEALERID,
+			Amount:     moneyFromCents(amounts[i]),
+			Balance:    to.BALANCE,
+		})
+		dealerIDs = append(dealerIDs, to.DEALERID)
+	}
+	if err := setAssetEvent(ctx, assetActionUpdate, dealerIDs...); err != nil {
+		return nil, err
+	}
+
+	logf(levelInfo, "BatchTransfer: %s paid %s to %d assets", fromDealerID, result.Total, len(recipients))
+	return result, nil
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// DeleteAssetsByStatus deletes every asset whose STATUS matches status and returns
+// how many were deleted. It is meant for resetting test environments and
+// requires an admin identity.
+// This is a rich query; on LevelDB it falls back to scanning every asset.
+func (s *SmartContract) DeleteAssetsByStatus(ctx contractapi.TransactionContextInterface, status string) (int, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return 0, err
+	}
+
+	status = strings.TrimSpace(status)
+	if status == "" {
+		return 0, fmt.Errorf("the status must not be empty")
+	}
+
+	query := map[string]interface{}{
+		"selector": map[string]interface{}{
+			"STATUS": status,
+		},
+		"use_index": []string{"_design/indexStatusDoc", "indexStatus"},
+	}
+	queryJSON, err := json.Marshal(query)
+	if err != nil {
+		return 0, err
+	}
+
+	// Collect the matches first so the iterator is closed before deleting
+	assets, err := getQueryResultForQueryString(ctx, string(queryJSON), func(asset *Asset) bool {
+		return asset.STATUS == status
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	dealerIDs := make([]string, 0, len(assets))
+	for _, asset := range assets {
+		if err := deleteAssetState(ctx, asset.DEALERID); err != nil {
+			return 0, err
+		}
+		dealerIDs = append(dealerIDs, asset.DEALERID)
+	}
+	if len(dealerIDs) > 0 {
+		if err := setAssetEvent(ctx, assetActionDelete, dealerIDs...); err != nil {
+			return 0, err
+		}
+	}
+
+	logf(levelInfo, "Deleted %d assets with STATUS %s", len(assets), status)
+	return len(assets), nil
+}
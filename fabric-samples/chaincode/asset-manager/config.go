@@ -0,0 +1,313 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// configObjectType is the composite key namespace for chaincode settings.
+// Composite keys are not returned by GetStateByRange, so settings never show up as assets.
+const configObjectType = "config"
+
+// ContractConfig holds chaincode-level settings. They live in the world state
+// rather than in environment variables so that every endorsing peer agrees on them.
+type ContractConfig struct {
+	// AllowDirectBalanceUpdates restores the old UpdateAsset behavior of
+	// overwriting BALANCE instead of requiring Deposit/Withdraw/TransferBalance
+	AllowDirectBalanceUpdates bool `json:"allowDirectBalanceUpdates"`
+
+	// DefaultStatus and DefaultRemarks are stored by CreateAsset when the
+	// client leaves STATUS or REMARKS empty
+	DefaultStatus  string `json:"defaultStatus"`
+	DefaultRemarks string `json:"defaultRemarks"`
+
+	// MaxBalance and MaxTransAmount are the largest BALANCE and TRANSAMOUNT
+	// writes may store, catching data-entry and overflow errors
+	MaxBalance     Money `json:"maxBalance"`
+	MaxTransAmount Money `json:"maxTransAmount"`
+
+	// MinBalance is the lowest BALANCE any write may leave. It is zero unless an
+	// admin allows an overdraft with SetMinBalance.
+	MinBalance Money `json:"minBalance"`
+
+	// MaxHistoryRecords is the most history records a single history query
+	// reads, so an asset with a long history can't tie up the peer
+	MaxHistoryRecords int `json:"maxHistoryRecords"`
+
+	// AllowedTransTypes is the TRANSTYPE vocabulary of the deployment. Any
+	// TRANSTYPE is accepted while it is empty.
+	AllowedTransTypes []string `json:"allowedTransTypes,omitempty"`
+
+	// AllowedCurrencies restricts the Currency of new assets. Every currency in
+	// currencyMinorUnits is accepted while it is empty.
+	AllowedCurrencies []string `json:"allowedCurrencies,omitempty"`
+}
+
+// Default amount limits, used until an admin calls SetAmountLimits
+const (
+	defaultMaxBalance     Money = "1000000000000.00"
+	defaultMaxTransAmount Money = "1000000000.00"
+)
+
+// defaultMinBalance forbids overdrafts until an admin calls SetMinBalance
+const defaultMinBalance Money = "0.00"
+
+// defaultMaxHistoryRecords is used until an admin calls SetMaxHistoryRecords
+const defaultMaxHistoryRecords = 1000
+
+// GetConfig returns the current chaincode settings
+func (s *SmartContract) GetConfig(ctx contractapi.TransactionContextInterface) (*ContractConfig, error) {
+	return getConfig(ctx)
+}
+
+// SetDirectBalanceUpdates lets an admin allow or forbid setting BALANCE through UpdateAsset
+func (s *SmartContract) SetDirectBalanceUpdates(ctx contractapi.TransactionContextInterface, allowed bool) error {
+	if err := requireAdmin(ctx); err != nil {
+		return err
+	}
+
+	config, err := getConfig(ctx)
+	if err != nil {
+		return err
+	}
+	config.AllowDirectBalanceUpdates = allowed
+
+	return putConfig(ctx, config)
+}
+
+// SetCreationDefaults lets an admin change the STATUS and REMARKS that
+// CreateAsset stores when the client leaves them empty
+func (s *SmartContract) SetCreationDefaults(ctx contractapi.TransactionContextInterface, status string, remarks string) error {
+	if err := requireAdmin(ctx); err != nil {
+		return err
+	}
+
+	status = strings.TrimSpace(status)
+	if err := validateStatus(status); err != nil {
+		return fmt.Errorf("invalid default status: %v", err)
+	}
+	if status == statusFrozen {
+		return fmt.Errorf("invalid default status: new assets cannot be created FROZEN")
+	}
+
+	config, err := getConfig(ctx)
+	if err != nil {
+		return err
+	}
+	config.DefaultStatus = status
+	config.DefaultRemarks = remarks
+
+	return putConfig(ctx, config)
+}
+
+// SetAmountLimits lets an admin change the largest BALANCE and TRANSAMOUNT
+// writes may store, given as decimal strings
+func (s *SmartContract) SetAmountLimits(ctx contractapi.TransactionContextInterface, maxBalance string, maxTransAmount string) error {
+	if err := requireAdmin(ctx); err != nil {
+		return err
+	}
+
+	maxBalanceValue, err := newMoney("maximum BALANCE", maxBalance)
+	if err != nil {
+		return err
+	}
+	maxTransAmountValue, err := newMoney("maximum TRANSAMOUNT", maxTransAmount)
+	if err != nil {
+		return err
+	}
+	if maxBalanceValue.cents() <= 0 || maxTransAmountValue.cents() <= 0 {
+		return fmt.Errorf("the amount limits must be positive, got %s and %s", maxBalanceValue, maxTransAmountValue)
+	}
+
+	config, err := getConfig(ctx)
+	if err != nil {
+		return err
+	}
+	config.MaxBalance = maxBalanceValue
+	config.MaxTransAmount = maxTransAmountValue
+
+	return putConfig(ctx, config)
+}
+
+// SetMinBalance lets an admin change the lowest BALANCE writes may leave, given as a
+// decimal string. It can't be positive: a negative value allows an overdraft down to it.
+// Stored assets below a raised minimum keep their BALANCE, but their next write must
+// bring it back to the minimum.
+func (s *SmartContract) SetMinBalance(ctx contractapi.TransactionContextInterface, minBalance string) error {
+	if err := requireAdmin(ctx); err != nil {
+		return err
+	}
+
+	minBalanceValue, err := newMoney("minimum BALANCE", minBalance)
+	if err != nil {
+		return err
+	}
+	if minBalanceValue.cents() > 0 {
+		return fmt.Errorf("the minimum BALANCE must be zero or negative, got %s", minBalanceValue)
+	}
+
+	config, err := getConfig(ctx)
+	if err != nil {
+		return err
+	}
+	config.MinBalance = minBalanceValue
+
+	return putConfig(ctx, config)
+}
+
+// SetMaxHistoryRecords lets an admin change the most records a history query returns
+func (s *SmartContract) SetMaxHistoryRecords(ctx contractapi.TransactionContextInterface, maxRecords int) error {
+	if err := requireAdmin(ctx); err != nil {
+		return err
+	}
+
+	if maxRecords <= 0 {
+		return fmt.Errorf("the maximum history records must be positive, got %d", maxRecords)
+	}
+
+	config, err := getConfig(ctx)
+	if err != nil {
+		return err
+	}
+	config.MaxHistoryRecords = maxRecords
+
+	return putConfig(ctx, config)
+}
+
+// InitConfig sets up the chaincode settings of a new deployment. It is meant to
+// be the --isInit call when the chaincode definition requires initialization, e.g.
+//
+//	'{"function":"InitConfig","Args":["[\"CREDIT\",\"DEBIT\"]"]}'
+//
+// allowedTransTypesJSON is a JSON array of TRANSTYPE values, or an empty array to
+// accept any. It fails once settings are stored, use the admin setters after that.
+func (s *SmartContract) InitConfig(ctx contractapi.TransactionContextInterface, allowedTransTypesJSON string) error {
+	if err := requireAdmin(ctx); err != nil {
+		return err
+	}
+
+	key, err := configKey(ctx)
+	if err != nil {
+		return err
+	}
+	existing, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return fmt.Errorf("failed to read config from world state: %v", err)
+	}
+	if existing != nil {
+		return fmt.Errorf("the chaincode settings are already initialized")
+	}
+
+	allowed, err := parseTransTypes(allowedTransTypesJSON)
+	if err != nil {
+		return err
+	}
+
+	config, err := getConfig(ctx)
+	if err != nil {
+		return err
+	}
+	config.AllowedTransTypes = allowed
+
+	return putConfig(ctx, config)
+}
+
+// SetAllowedTransTypes lets an admin replace the accepted TRANSTYPE values, given
+// as a JSON array. An empty array accepts any TRANSTYPE. Stored assets keep their
+// TRANSTYPE, but writes to them must then use an allowed one.
+func (s *SmartContract) SetAllowedTransTypes(ctx contractapi.TransactionContextInterface, allowedTransTypesJSON string) error {
+	if err := requireAdmin(ctx); err != nil {
+		return err
+	}
+
+	allowed, err := parseTransTypes(allowedTransTypesJSON)
+	if err != nil {
+		return err
+	}
+
+	config, err := getConfig(ctx)
+	if err != nil {
+		return err
+	}
+	config.AllowedTransTypes = allowed
+
+	return putConfig(ctx, config)
+}
+
+// parseTransTypes reads a JSON array of TRANSTYPE values, trimming them and dropping duplicates
+func parseTransTypes(transTypesJSON string) ([]string, error) {
+	var transTypes []string
+	if err := json.Unmarshal([]byte(transTypesJSON), &transTypes); err != nil {
+		return nil, fmt.Errorf("the transaction types must be a JSON array of strings: %v", err)
+	}
+
+	seen := make(map[string]bool)
+	var allowed []string
+	for _, transType := range transTypes {
+		transType = strings.TrimSpace(transType)
+		if transType == "" {
+			return nil, fmt.Errorf("the transaction types must not be empty")
+		}
+		if !seen[transType] {
+			seen[transType] = true
+			allowed = append(allowed, transType)
+		}
+	}
+	return allowed, nil
+}
+
+// configKey is the world state key the chaincode settings are stored under
+func configKey(ctx contractapi.TransactionContextInterface) (string, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(configObjectType, []string{"contract"})
+	if err != nil {
+		return "", fmt.Errorf("failed to create config key: %v", err)
+	}
+	return key, nil
+}
+
+// getConfig reads the chaincode settings, returning the defaults if none were stored yet
+func getConfig(ctx contractapi.TransactionContextInterface) (*ContractConfig, error) {
+	key, err := configKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	configJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config from world state: %v", err)
+	}
+
+	config := ContractConfig{
+		DefaultStatus:  statusActive,
+		MaxBalance:     defaultMaxBalance,
+		MaxTransAmount: defaultMaxTransAmount,
+		MinBalance:     defaultMinBalance,
+
+		MaxHistoryRecords: defaultMaxHistoryRecords,
+	}
+	if configJSON != nil {
+		if err := json.Unmarshal(configJSON, &config); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal config JSON: %v", err)
+		}
+	}
+
+	return &config, nil
+}
+
+// putConfig writes the chaincode settings to the world state
+func putConfig(ctx contractapi.TransactionContextInterface, config *ContractConfig) error {
+	key, err := configKey(ctx)
+	if err != nil {
+		return err
+	}
+
+	configJSON, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+
+	return ctx.GetStub().PutState(key, configJSON)
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// currencyMinorUnits lists the ISO 4217 currencies an asset may hold, with the
+// number of decimal places each uses. Money keeps two places, so currencies with
+// three (BHD, KWD, ...) can't be represented and aren't listed; amounts in a
+// currency with none, such as JPY, must be whole.
+var currencyMinorUnits = map[string]int{
+	"AED": 2, "AUD": 2, "BDT": 2, "BRL": 2, "CAD": 2, "CHF": 2, "CLP": 0, "CNY": 2,
+	"CZK": 2, "DKK": 2, "EGP": 2, "EUR": 2, "GBP": 2, "GHS": 2, "HKD": 2, "HUF": 2,
+	"IDR": 2, "ILS": 2, "INR": 2, "ISK": 0, "JPY": 0, "KES": 2, "KRW": 0, "LKR": 2,
+	"MXN": 2, "MYR": 2, "NGN": 2, "NOK": 2, "NPR": 2, "NZD": 2, "PHP": 2, "PKR": 2,
+	"PLN": 2, "QAR": 2, "SAR": 2, "SEK": 2, "SGD": 2, "THB": 2, "TRY": 2, "TZS": 2,
+	"UGX": 0, "USD": 2, "VND": 0, "XAF": 0, "XOF": 0, "ZAR": 2,
+}
+
+// conversionRatePattern is a positive decimal rate with at most eight decimal places
+var conversionRatePattern = regexp.MustCompile(`^[0-9]+(\.[0-9]{1,8})?$`)
+
+// normalizeCurrency trims and uppercases a currency code, so "usd" is USD
+func normalizeCurrency(currency string) string {
+	return strings.ToUpper(strings.TrimSpace(currency))
+}
+
+// validateCurrency checks that currency is a supported ISO 4217 code and, when
+// allowed isn't empty, one of the deployment's currencies
+func validateCurrency(currency string, allowed []string) error {
+	if _, ok := currencyMinorUnits[currency]; !ok {
+		return fmt.Errorf("Currency must be a supported ISO 4217 code such as USD, got %q", currency)
+	}
+	if len(allowed) == 0 {
+		return nil
+	}
+	for _, code := range allowed {
+		if code == currency {
+			return nil
+		}
+	}
+	return fmt.Errorf("Currency %s is not allowed, expected one of %s", currency, strings.Join(allowed, ", "))
+}
+
+// validateCurrencyPrecision checks that the asset's amounts fit its currency's
+// decimal places. Assets without a Currency predate it and aren't checked.
+func validateCurrencyPrecision(asset *Asset) error {
+	units, ok := currencyMinorUnits[asset.Currency]
+	if !ok || units >= 2 {
+		return nil
+	}
+	step := currencyStep(asset.Currency)
+	if asset.BALANCE.cents()%step != 0 {
+		return fmt.Errorf("BALANCE %s has more decimal places than %s allows (%d)", asset.BALANCE, asset.Currency, units)
+	}
+	if asset.TRANSAMOUNT.cents()%step != 0 {
+		return fmt.Errorf("TRANSAMOUNT %s has more decimal places than %s allows (%d)", asset.TRANSAMOUNT, asset.Currency, units)
+	}
+	return nil
+}
+
+// currencyStep is the smallest amount of the currency in cents, 1 for currencies
+// with two decimal places and 100 for those with none
+func currencyStep(currency string) int64 {
+	step := int64(1)
+	if units, ok := currencyMinorUnits[currency]; ok {
+		for i := units; i < 2; i++ {
+			step *= 10
+		}
+	}
+	return step
+}
+
+// convertAmount converts cents with rate, a decimal string, rounding half up to
+// the smallest amount of the target currency
+func convertAmount(cents int64, rate string, toCurrency string) (int64, error) {
+	rate = strings.TrimSpace(rate)
+	if !conversionRatePattern.MatchString(rate) {
+		return 0, fmt.Errorf("the conversion rate must be a decimal number with at most eight decimal places, got %q", rate)
+	}
+	rateValue, ok := new(big.Rat).SetString(rate)
+	if !ok || rateValue.Sign() <= 0 {
+		return 0, fmt.Errorf("the conversion rate must be positive, got %q", rate)
+	}
+
+	// round(cents * rate / step) * step, both sides are positive
+	step := currencyStep(toCurrency)
+	converted := new(big.Rat).Mul(new(big.Rat).SetInt64(cents), rateValue)
+	converted.Quo(converted, new(big.Rat).SetInt64(step))
+	num := new(big.Int).Mul(converted.Num(), big.NewInt(2))
+	num.Add(num, converted.Denom())
+	rounded := num.Quo(num, new(big.Int).Mul(converted.Denom(), big.NewInt(2)))
+	if !rounded.IsInt64() {
+		return 0, fmt.Errorf("the converted amount is out of range")
+	}
+	return rounded.Int64() * step, nil
+}
+
+// SetAllowedCurrencies lets an admin restrict the currencies new assets may use,
+// given as a JSON array of ISO 4217 codes. An empty array allows every supported
+// currency. Stored assets keep their Currency.
+func (s *SmartContract) SetAllowedCurrencies(ctx contractapi.TransactionContextInterface, allowedCurrenciesJSON string) error {
+	if err := requireAdmin(ctx); err != nil {
+		return err
+	}
+
+	var currencies []string
+	if err := json.Unmarshal([]byte(allowedCurrenciesJSON), &currencies); err != nil {
+		return fmt.Errorf("the currencies must be a JSON array of strings: %v", err)
+	}
+	seen := make(map[string]bool)
+	var allowed []string
+	for _, currency := range currencies {
+		currency = normalizeCurrency(currency)
+		if err := validateCurrency(currency, nil); err != nil {
+			return err
+		}
+		if !seen[currency] {
+			seen[currency] = true
+			allowed = append(allowed, currency)
+		}
+	}
+	sort.Strings(allowed)
+
+	config, err := getConfig(ctx)
+	if err != nil {
+		return err
+	}
+	config.AllowedCurrencies = allowed
+
+	return putConfig(ctx, config)
+}
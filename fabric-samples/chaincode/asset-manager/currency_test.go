@@ -0,0 +1,77 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestConvertAmount(t *testing.T) {
+	tests := []struct {
+		cents      int64
+		rate       string
+		toCurrency string
+		converted  int64
+	}{
+		{10000, "1", "EUR", 10000},
+		{10000, "0.92", "EUR", 9200},
+		{10000, " 0.92 ", "EUR", 9200},
+		{12345, "83.12345678", "INR", 1026159},
+		// Half a cent rounds up, less than half rounds down
+		{1, "0.5", "EUR", 1},
+		{1, "0.49999999", "EUR", 0},
+		{3, "0.5", "EUR", 2},
+		// JPY has no decimal places, so the result is whole yen
+		{10000, "151.234", "JPY", 1512300},
+		{10000, "151.235", "JPY", 1512400},
+		{149, "1", "JPY", 100},
+		{150, "1", "JPY", 200},
+		{10, "1", "JPY", 0},
+	}
+	for _, test := range tests {
+		converted, err := convertAmount(test.cents, test.rate, test.toCurrency)
+		if err != nil {
+			t.Errorf("convertAmount(%d, %q, %s) returned error: %v", test.cents, test.rate, test.toCurrency, err)
+			continue
+		}
+		if converted != test.converted {
+			t.Errorf("convertAmount(%d, %q, %s) = %d, want %d", test.cents, test.rate, test.toCurrency, converted, test.converted)
+		}
+	}
+}
+
+func TestConvertAmountRejectsInvalidRates(t *testing.T) {
+	tests := []string{
+		"",
+		"abc",
+		"-1",
+		"+1",
+		"1e3",
+		"1.",
+		".5",
+		"1.123456789",
+		"0",
+		"0.00000000",
+	}
+	for _, rate := range tests {
+		if converted, err := convertAmount(10000, rate, "EUR"); err == nil {
+			t.Errorf("convertAmount(10000, %q, EUR) = %d, want an error", rate, converted)
+		}
+	}
+}
+
+func TestConvertAmountOutOfRange(t *testing.T) {
+	_, err := convertAmount(math.MaxInt64, "2", "EUR")
+	if err == nil || !strings.Contains(err.Error(), "out of range") {
+		t.Errorf("convertAmount(MaxInt64, \"2\", EUR) error = %v, want out of range", err)
+	}
+}
+
+func TestCurrencyStep(t *testing.T) {
+	tests := map[string]int64{"USD": 1, "EUR": 1, "JPY": 100, "KRW": 100, "XXX": 1}
+	for currency, step := range tests {
+		if got := currencyStep(currency); got != step {
+			t.Errorf("currencyStep(%s) = %d, want %d", currency, got, step)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"encoding/json"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// assetEventName is the chaincode event set by every transaction that writes or
+// deletes assets, except TransferBalance which sets TransferEvent instead
+const assetEventName = "AssetEvent"
+
+// AssetEvent actions
+const (
+	assetActionCreate = "CREATE"
+	assetActionUpdate = "UPDATE"
+	assetActionDelete = "DELETE"
+)
+
+// AssetEvent tells listeners which assets a transaction changed
+type AssetEvent struct {
+	TxID      string   `json:"txId"`
+	Action    string   `json:"action"`
+	DealerIDs []string `json:"dealerIds"`
+}
+
+// setAssetEvent sets the transaction's AssetEvent. A transaction carries only one
+// event, so a later call replaces an earlier one; transactions that touch several
+// assets set it again at the end with all of them.
+func setAssetEvent(ctx contractapi.TransactionContextInterface, action string, dealerIDs ...string) error {
+	eventJSON, err := json.Marshal(AssetEvent{
+		TxID:      ctx.GetStub().GetTxID(),
+		Action:    action,
+		DealerIDs: dealerIDs,
+	})
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().SetEvent(assetEventName, eventJSON)
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Asset STATUS values with special meaning to the chaincode
+const (
+	statusActive = "ACTIVE"
+	statusFrozen = "FROZEN"
+)
+
+// FreezeAsset sets STATUS to FROZEN, which blocks Deposit, Withdraw and TransferBalance.
+// Freezing a frozen asset does nothing. It requires an admin identity, as does
+// UnfreezeAsset.
+func (s *SmartContract) FreezeAsset(ctx contractapi.TransactionContextInterface, dealerID string) error {
+	dealerID = normalizeDealerID(dealerID)
+	return s.setFrozen(ctx, dealerID, true)
+}
+
+// UnfreezeAsset sets STATUS of a frozen asset back to ACTIVE so it can transact
+// again. Any other STATUS is an error, so a CLOSED or SUSPENDED asset isn't
+// reactivated by mistake.
+func (s *SmartContract) UnfreezeAsset(ctx contractapi.TransactionContextInterface, dealerID string) error {
+	dealerID = normalizeDealerID(dealerID)
+	return s.setFrozen(ctx, dealerID, false)
+}
+
+// setFrozen freezes or unfreezes an existing asset. Only an admin may freeze or
+// unfreeze.
+func (s *SmartContract) setFrozen(ctx contractapi.TransactionContextInterface, dealerID string, frozen bool) error {
+	if err := requireAdmin(ctx); err != nil {
+		return err
+	}
+
+	asset, err := s.ReadAsset(ctx, dealerID)
+	if err != nil {
+		return err
+	}
+
+	status := statusActive
+	if frozen {
+		if asset.STATUS == statusFrozen {
+			return nil
+		}
+		status = statusFrozen
+	} else if asset.STATUS != statusFrozen {
+		return fmt.Errorf("the asset %s is not frozen, its STATUS is %s", dealerID, asset.STATUS)
+	}
+
+	logf(levelInfo, "Setting STATUS of asset %s from %s to %s", dealerID, asset.STATUS, status)
+	asset.STATUS = status
+
+	return putAsset(ctx, asset)
+}
+
+// SetAssetStatus changes only the STATUS of an existing asset. Freezing and
+// unfreezing have their own transactions, so FROZEN can't be set or cleared here.
+func (s *SmartContract) SetAssetStatus(ctx contractapi.TransactionContextInterface, dealerID string, status string) error {
+	dealerID = normalizeDealerID(dealerID)
+	status = strings.TrimSpace(status)
+	if err := validateStatus(status); err != nil {
+		return err
+	}
+
+	asset, err := s.ReadAsset(ctx, dealerID)
+	if err != nil {
+		return err
+	}
+	if status == statusFrozen {
+		return fmt.Errorf("use FreezeAsset to freeze asset %s", dealerID)
+	}
+	if err := requireNotFrozen(asset); err != nil {
+		return fmt.Errorf("%v, use UnfreezeAsset before changing its STATUS", err)
+	}
+	if asset.STATUS == status {
+		return nil
+	}
+
+	logf(levelInfo, "Setting STATUS of asset %s from %s to %s", dealerID, asset.STATUS, status)
+	asset.STATUS = status
+	return putAsset(ctx, asset)
+}
+
+// requireNotFrozen returns an error when the asset is frozen
+func requireNotFrozen(asset *Asset) error {
+	if asset.STATUS == statusFrozen {
+		return fmt.Errorf("the asset %s is frozen", asset.DEALERID)
+	}
+	return nil
+}
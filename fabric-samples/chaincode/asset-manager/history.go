@@ -0,0 +1,309 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-protos-go/ledger/queryresult"
+)
+
+// GetLatestAssetTransaction returns only the most recent history entry for an asset.
+// It returns nil when the asset has no history at all.
+func (s *SmartContract) GetLatestAssetTransaction(ctx contractapi.TransactionContextInterface, dealerID string) (*HistoryQueryResult, error) {
+	dealerID = normalizeDealerID(dealerID)
+	logf(levelInfo, "GetLatestAssetTransaction: ID %s", dealerID)
+
+	resultsIterator, err := ctx.GetStub().GetHistoryForKey(dealerID)
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	var latest *HistoryQueryResult
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		record, err := newHistoryQueryResult(dealerID, response)
+		if err != nil {
+			return nil, err
+		}
+
+		// Don't rely on the iterator order, compare timestamps instead
+		if latest == nil || record.Timestamp.After(latest.Timestamp) {
+			latest = record
+		}
+	}
+
+	return latest, nil
+}
+
+// GetAssetAtTime returns the asset as it was at the given RFC3339 timestamp, that is
+// the last history record written at or before that time
+func (s *SmartContract) GetAssetAtTime(ctx contractapi.TransactionContextInterface, dealerID string, timestampRFC3339 string) (*Asset, error) {
+	dealerID = normalizeDealerID(dealerID)
+	logf(levelInfo, "GetAssetAtTime: ID %s, time %s", dealerID, timestampRFC3339)
+
+	at, err := time.Parse(time.RFC3339, timestampRFC3339)
+	if err != nil {
+		return nil, fmt.Errorf("invalid time %q, expected RFC3339: %v", timestampRFC3339, err)
+	}
+
+	resultsIterator, err := ctx.GetStub().GetHistoryForKey(dealerID)
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	var current *HistoryQueryResult
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		record, err := newHistoryQueryResult(dealerID, response)
+		if err != nil {
+			return nil, err
+		}
+
+		// Don't rely on the iterator order, compare timestamps instead
+		if record.Timestamp.After(at) {
+			continue
+		}
+		if current == nil || record.Timestamp.After(current.Timestamp) {
+			current = record
+		}
+	}
+
+	if current == nil || current.IsDelete {
+		return nil, fmt.Errorf("the asset %s does not exist at %s", dealerID, timestampRFC3339)
+	}
+	return current.Record, nil
+}
+
+// HistoryPage is one page of an asset's history
+type HistoryPage struct {
+	Records    []HistoryQueryResult `json:"records"`
+	NextOffset int                  `json:"nextOffset"` // Offset to request the next page with
+	HasMore    bool                 `json:"hasMore"`
+}
+
+// GetAssetHistoryPage returns at most limit history entries for an asset,
+// skipping the first offset entries, so large histories can be fetched in pages.
+// A limit above MaxHistoryRecords is lowered to it.
+func (s *SmartContract) GetAssetHistoryPage(ctx contractapi.TransactionContextInterface, dealerID string, limit int, offset int) (*HistoryPage, error) {
+	dealerID = normalizeDealerID(dealerID)
+	logf(levelInfo, "GetAssetHistoryPage: ID %s, limit %d, offset %d", dealerID, limit, offset)
+
+	if limit <= 0 {
+		return nil, fmt.Errorf("limit must be positive, got %d", limit)
+	}
+	if offset < 0 {
+		return nil, fmt.Errorf("offset must not be negative, got %d", offset)
+	}
+
+	config, err := getConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if limit > config.MaxHistoryRecords {
+		limit = config.MaxHistoryRecords
+	}
+
+	resultsIterator, err := ctx.GetStub().GetHistoryForKey(dealerID)
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	page := &HistoryPage{
+		Records: []HistoryQueryResult{},
+	}
+
+	// Skip the entries returned by earlier pages
+	for skipped := 0; skipped < offset && resultsIterator.HasNext(); skipped++ {
+		if _, err := resultsIterator.Next(); err != nil {
+			return nil, err
+		}
+	}
+
+	for len(page.Records) < limit && resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		record, err := newHistoryQueryResult(dealerID, response)
+		if err != nil {
+			return nil, err
+		}
+
+		page.Records = append(page.Records, *record)
+	}
+
+	page.HasMore = resultsIterator.HasNext()
+	page.NextOffset = offset + len(page.Records)
+
+	return page, nil
+}
+
+// GetAssetHistoryWithChanges returns the asset's history like GetAssetHistory,
+// with each record listing the fields that changed since the previous record.
+// The first record, and the first record after a delete, have no previous state to compare with.
+func (s *SmartContract) GetAssetHistoryWithChanges(ctx contractapi.TransactionContextInterface, dealerID string) ([]HistoryQueryResult, error) {
+	dealerID = normalizeDealerID(dealerID)
+	records, err := s.GetAssetHistory(ctx, dealerID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := addHistoryChanges(records); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// BoundedHistory is an asset's history capped at MaxHistoryRecords
+type BoundedHistory struct {
+	Records    []HistoryQueryResult `json:"records"`
+	Truncated  bool                 `json:"truncated"` // The asset has more history than was returned
+	MaxRecords int                  `json:"maxRecords"`
+}
+
+// GetBoundedAssetHistory returns the asset's history like GetAssetHistory, or like
+// GetAssetHistoryWithChanges when withChanges is set, and whether it was truncated
+// at MaxHistoryRecords. Use GetAssetHistoryPage to read past the maximum.
+func (s *SmartContract) GetBoundedAssetHistory(ctx contractapi.TransactionContextInterface, dealerID string, withChanges bool) (*BoundedHistory, error) {
+	dealerID = normalizeDealerID(dealerID)
+	logf(levelInfo, "GetBoundedAssetHistory: ID %s, changes %t", dealerID, withChanges)
+
+	config, err := getConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	records, truncated, err := readAssetHistory(ctx, dealerID, config.MaxHistoryRecords)
+	if err != nil {
+		return nil, err
+	}
+	if withChanges {
+		if err := addHistoryChanges(records); err != nil {
+			return nil, err
+		}
+	}
+
+	if records == nil {
+		records = []HistoryQueryResult{}
+	}
+	return &BoundedHistory{
+		Records:    records,
+		Truncated:  truncated,
+		MaxRecords: config.MaxHistoryRecords,
+	}, nil
+}
+
+// addHistoryChanges fills in the fields that changed for each history record
+func addHistoryChanges(records []HistoryQueryResult) error {
+	// Compare in chronological order whatever order the history came back in
+	chronological := make([]*HistoryQueryResult, len(records))
+	for i := range records {
+		chronological[i] = &records[i]
+	}
+	sort.SliceStable(chronological, func(i, j int) bool {
+		return chronological[i].Timestamp.Before(chronological[j].Timestamp)
+	})
+
+	var previous *Asset
+	for _, record := range chronological {
+		if record.IsDelete {
+			previous = nil
+			continue
+		}
+		if previous != nil {
+			changes, err := diffAssets(previous, record.Record)
+			if err != nil {
+				return err
+			}
+			record.Changes = changes
+		}
+		previous = record.Record
+	}
+	return nil
+}
+
+// diffAssets returns the fields, by JSON name, whose values differ between two assets
+func diffAssets(previous *Asset, current *Asset) (map[string]FieldChange, error) {
+	oldFields, err := assetFieldValues(previous)
+	if err != nil {
+		return nil, err
+	}
+	newFields, err := assetFieldValues(current)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make(map[string]FieldChange)
+	for name, newValue := range newFields {
+		if oldValue := oldFields[name]; oldValue != newValue {
+			changes[name] = FieldChange{Old: oldValue, New: newValue}
+		}
+	}
+	for name, oldValue := range oldFields {
+		if _, ok := newFields[name]; !ok {
+			changes[name] = FieldChange{Old: oldValue, New: ""}
+		}
+	}
+	return changes, nil
+}
+
+// assetFieldValues flattens an asset into its JSON field names and string values
+func assetFieldValues(asset *Asset) (map[string]string, error) {
+	assetJSON, err := json.Marshal(asset)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(assetJSON, &fields); err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]string, len(fields))
+	for name, raw := range fields {
+		var str string
+		if err := json.Unmarshal(raw, &str); err == nil {
+			values[name] = str
+		} else {
+			values[name] = string(raw)
+		}
+	}
+	return values, nil
+}
+
+// newHistoryQueryResult converts a key modification from the history iterator
+// into a HistoryQueryResult. Deletes have no value, so only the DEALERID is set.
+func newHistoryQueryResult(dealerID string, response *queryresult.KeyModification) (*HistoryQueryResult, error) {
+	var asset Asset
+	if len(response.Value) > 0 {
+		err := json.Unmarshal(response.Value, &asset)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		asset = Asset{
+			DEALERID: dealerID,
+		}
+	}
+
+	return &HistoryQueryResult{
+		TxId:      response.TxId,
+		Timestamp: response.Timestamp.AsTime(),
+		Record:    &asset,
+		IsDelete:  response.IsDelete,
+	}, nil
+}
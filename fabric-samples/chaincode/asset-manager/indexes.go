@@ -0,0 +1,185 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// secondaryIndex is a composite key index (name~value~dealerID) over an asset field,
+// so assets can be found by that field with GetStateByPartialCompositeKey on any state database
+type secondaryIndex struct {
+	name  string
+	value func(asset *Asset) string
+}
+
+// secondaryIndexes are kept up to date by putAsset and deleteAssetState
+var secondaryIndexes = []secondaryIndex{
+	{name: "status~dealerID", value: func(asset *Asset) string { return asset.STATUS }},
+}
+
+// retiredIndexes are no longer maintained and are deleted by ReindexAssets.
+// msisdn~dealerID put the MSISDN in public keys, which are now private data.
+var retiredIndexes = []secondaryIndex{
+	{name: "msisdn~dealerID"},
+}
+
+// indexEntryValue is stored under every index key, the key itself carries the data
+var indexEntryValue = []byte{0x00}
+
+// ReindexAssets rebuilds every secondary index from the asset records, deleting
+// all existing entries first so stale ones left by migrations or bugs disappear.
+// It returns the number of index entries written and requires an admin identity.
+func (s *SmartContract) ReindexAssets(ctx contractapi.TransactionContextInterface) (int, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return 0, err
+	}
+
+	for _, index := range append(retiredIndexes, secondaryIndexes...) {
+		if err := clearIndex(ctx, index); err != nil {
+			return 0, err
+		}
+	}
+
+	resultsIterator, err := ctx.GetStub().GetStateByRange("", "")
+	if err != nil {
+		return 0, fmt.Errorf("failed to get state by range: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	rebuilt := 0
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return 0, fmt.Errorf("failed to get next state from iterator: %v", err)
+		}
+
+		var asset Asset
+		if err := json.Unmarshal(queryResponse.Value, &asset); err != nil {
+			return 0, fmt.Errorf("failed to unmarshal asset JSON: %v", err)
+		}
+		for _, index := range secondaryIndexes {
+			written, err := putIndexEntry(ctx, index, &asset)
+			if err != nil {
+				return 0, err
+			}
+			if written {
+				rebuilt++
+			}
+		}
+	}
+
+	logf(levelInfo, "Rebuilt %d secondary index entries", rebuilt)
+	return rebuilt, nil
+}
+
+// clearIndex deletes every entry of an index
+func clearIndex(ctx contractapi.TransactionContextInterface, index secondaryIndex) error {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(index.name, []string{})
+	if err != nil {
+		return fmt.Errorf("failed to read index %s: %v", index.name, err)
+	}
+	defer resultsIterator.Close()
+
+	for resultsIterator.HasNext() {
+		entry, err := resultsIterator.Next()
+		if err != nil {
+			return fmt.Errorf("failed to read index %s: %v", index.name, err)
+		}
+		if err := ctx.GetStub().DelState(entry.Key); err != nil {
+			return fmt.Errorf("failed to delete index entry from %s: %v", index.name, err)
+		}
+	}
+	return nil
+}
+
+// updateIndexes moves the asset's index entries from its previous to its current
+// values. previous is nil for a new asset and current is nil for a deleted one.
+func updateIndexes(ctx contractapi.TransactionContextInterface, previous *Asset, current *Asset) error {
+	for _, index := range secondaryIndexes {
+		if previous != nil && (current == nil || index.value(previous) != index.value(current)) {
+			if err := deleteIndexEntry(ctx, index, previous); err != nil {
+				return err
+			}
+		}
+		if current != nil && (previous == nil || index.value(previous) != index.value(current)) {
+			if _, err := putIndexEntry(ctx, index, current); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// putIndexEntry writes the asset's entry in an index. Assets with an empty value
+// for the field aren't indexed, so it reports whether an entry was written.
+func putIndexEntry(ctx contractapi.TransactionContextInterface, index secondaryIndex, asset *Asset) (bool, error) {
+	value := index.value(asset)
+	if value == "" {
+		return false, nil
+	}
+
+	key, err := ctx.GetStub().CreateCompositeKey(index.name, []string{value, asset.DEALERID})
+	if err != nil {
+		return false, fmt.Errorf("failed to create %s key: %v", index.name, err)
+	}
+	if err := ctx.GetStub().PutState(key, indexEntryValue); err != nil {
+		return false, fmt.Errorf("failed to write %s entry: %v", index.name, err)
+	}
+	return true, nil
+}
+
+// deleteIndexEntry removes the asset's entry from an index
+func deleteIndexEntry(ctx contractapi.TransactionContextInterface, index secondaryIndex, asset *Asset) error {
+	value := index.value(asset)
+	if value == "" {
+		return nil
+	}
+
+	key, err := ctx.GetStub().CreateCompositeKey(index.name, []string{value, asset.DEALERID})
+	if err != nil {
+		return fmt.Errorf("failed to create %s key: %v", index.name, err)
+	}
+	if err := ctx.GetStub().DelState(key); err != nil {
+		return fmt.Errorf("failed to delete %s entry: %v", index.name, err)
+	}
+	return nil
+}
+
+// readStoredAsset returns the asset currently in the world state, or nil if there is none
+func readStoredAsset(ctx contractapi.TransactionContextInterface, dealerID string) (*Asset, error) {
+	assetJSON, err := ctx.GetStub().GetState(dealerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if assetJSON == nil {
+		return nil, nil
+	}
+
+	var asset Asset
+	if err := json.Unmarshal(assetJSON, &asset); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal asset JSON: %v", err)
+	}
+	return &asset, nil
+}
+
+// deleteAssetState deletes an asset, its private details and its index entries,
+// and sets the AssetEvent
+func deleteAssetState(ctx contractapi.TransactionContextInterface, dealerID string) error {
+	previous, err := readStoredAsset(ctx, dealerID)
+	if err != nil {
+		return err
+	}
+
+	if err := ctx.GetStub().DelState(dealerID); err != nil {
+		return fmt.Errorf("failed to delete asset %s: %v", dealerID, err)
+	}
+	if err := deletePrivateFields(ctx, dealerID); err != nil {
+		return err
+	}
+	if err := updateIndexes(ctx, previous, nil); err != nil {
+		return err
+	}
+	return setAssetEvent(ctx, assetActionDelete, dealerID)
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// sampleAssets seed a new ledger for demos and testing
+var sampleAssets = []Asset{
+	{DEALERID: "DEALER001", MSISDN: "9000000001", BALANCE: "1000.00", STATUS: statusActive, TRANSAMOUNT: "0.00", REMARKS: "Sample asset", Currency: "USD"},
+	{DEALERID: "DEALER002", MSISDN: "9000000002", BALANCE: "2500.50", STATUS: statusActive, TRANSAMOUNT: "0.00", REMARKS: "Sample asset", Currency: "USD"},
+	{DEALERID: "DEALER003", MSISDN: "9000000003", BALANCE: "0.00", STATUS: statusActive, TRANSAMOUNT: "0.00", REMARKS: "Sample asset", Currency: "USD"},
+	{DEALERID: "DEALER004", MSISDN: "9000000004", BALANCE: "750.25", STATUS: "INACTIVE", TRANSAMOUNT: "0.00", REMARKS: "Sample asset", Currency: "USD"},
+	{DEALERID: "DEALER005", MSISDN: "9000000005", BALANCE: "10000.00", STATUS: statusActive, TRANSAMOUNT: "0.00", REMARKS: "Sample asset", Currency: "USD"},
+}
+
+// InitLedger lets an admin seed the ledger with a few sample assets, like the
+// other fabric-samples chaincodes. Sample assets that already exist are left
+// alone, so it never overwrites data. It returns how many assets were created.
+func (s *SmartContract) InitLedger(ctx contractapi.TransactionContextInterface) (int, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return 0, err
+	}
+
+	createdAt, err := txTimestamp(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var created []string
+	for _, sample := range sampleAssets {
+		exists, err := s.AssetExists(ctx, sample.DEALERID)
+		if err != nil {
+			return 0, err
+		}
+		if exists {
+			logf(levelInfo, "InitLedger: asset %s already exists, skipped", sample.DEALERID)
+			continue
+		}
+
+		asset := sample
+		asset.CreatedAt = createdAt.Format(createdAtLayout)
+		if err := validateAsset(&asset); err != nil {
+			return 0, err
+		}
+		if err := checkConfiguredLimits(ctx, &asset); err != nil {
+			return 0, err
+		}
+		if err := putAsset(ctx, &asset); err != nil {
+			return 0, fmt.Errorf("failed to create sample asset %s: %v", asset.DEALERID, err)
+		}
+		created = append(created, asset.DEALERID)
+	}
+	if len(created) > 0 {
+		if err := setAssetEvent(ctx, assetActionCreate, created...); err != nil {
+			return 0, err
+		}
+	}
+
+	logf(levelInfo, "InitLedger: created %d sample assets", len(created))
+	return len(created), nil
+}
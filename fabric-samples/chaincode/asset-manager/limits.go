@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// checkConfiguredLimits rejects an asset whose BALANCE is below the configured
+// minimum, whose BALANCE or TRANSAMOUNT exceeds the configured maximums or has
+// more decimal places than its Currency, or whose TRANSTYPE isn't one of the
+// allowed transaction types. Every write that stores a BALANCE calls it.
+func checkConfiguredLimits(ctx contractapi.TransactionContextInterface, asset *Asset) error {
+	config, err := getConfig(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := checkBalanceFloor(asset, config.MinBalance); err != nil {
+		return err
+	}
+	if err := checkAmount("BALANCE", asset.BALANCE, config.MaxBalance); err != nil {
+		return fmt.Errorf("asset %s: %v", asset.DEALERID, err)
+	}
+	if err := checkAmount("TRANSAMOUNT", asset.TRANSAMOUNT, config.MaxTransAmount); err != nil {
+		return fmt.Errorf("asset %s: %v", asset.DEALERID, err)
+	}
+	if err := checkTransType(asset.TRANSTYPE, config.AllowedTransTypes); err != nil {
+		return fmt.Errorf("asset %s: %v", asset.DEALERID, err)
+	}
+	if err := validateCurrencyPrecision(asset); err != nil {
+		return fmt.Errorf("asset %s: %v", asset.DEALERID, err)
+	}
+	return nil
+}
+
+// checkBalanceFloor rejects a BALANCE below minBalance. Withdraw, TransferBalance,
+// UpdateAsset and every other write report an overdraft with this same error.
+func checkBalanceFloor(asset *Asset, minBalance Money) error {
+	if asset.BALANCE.cents() < minBalance.cents() {
+		return fmt.Errorf("insufficient balance in asset %s: BALANCE would be %s, below the minimum of %s", asset.DEALERID, asset.BALANCE, minBalance)
+	}
+	return nil
+}
+
+// checkAmount checks a single monetary value against its limit
+func checkAmount(field string, value Money, max Money) error {
+	if value.cents() > max.cents() {
+		return fmt.Errorf("%s of %s exceeds the maximum of %s", field, value, max)
+	}
+	return nil
+}
+
+// checkTransType checks a TRANSTYPE against the allowed transaction types. An
+// empty TRANSTYPE records no transaction, so it is always accepted.
+func checkTransType(transType string, allowed []string) error {
+	if transType == "" || len(allowed) == 0 {
+		return nil
+	}
+	for _, allowedType := range allowed {
+		if transType == allowedType {
+			return nil
+		}
+	}
+	return fmt.Errorf("TRANSTYPE %q is not one of the allowed transaction types %s", transType, strings.Join(allowed, ", "))
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"log"
+	"os"
+	"strings"
+)
+
+// logLevel gates the chaincode's log output. It is read from the LOG_LEVEL
+// environment variable (debug, info, warn or error) and defaults to info.
+// Logging doesn't affect the transaction result, so reading the environment is safe here.
+type logLevel int
+
+const (
+	levelDebug logLevel = iota
+	levelInfo
+	levelWarn
+	levelError
+)
+
+var currentLogLevel = parseLogLevel(os.Getenv("LOG_LEVEL"))
+
+// parseLogLevel converts a LOG_LEVEL value into a logLevel, falling back to info
+func parseLogLevel(value string) logLevel {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "debug":
+		return levelDebug
+	case "warn", "warning":
+		return levelWarn
+	case "error":
+		return levelError
+	default:
+		return levelInfo
+	}
+}
+
+// logf writes a log line only when level is at or above the configured LOG_LEVEL
+func logf(level logLevel, format string, args ...interface{}) {
+	if level >= currentLogLevel {
+		log.Printf(format, args...)
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Limits on an asset's Metadata, so deployment specific fields can't bloat the
+// world state. Lengths are in bytes.
+const (
+	maxMetadataEntries     = 20
+	maxMetadataKeyLength   = 64
+	maxMetadataValueLength = 256
+)
+
+// parseMetadata reads the metadataJSON argument of CreateAsset and UpdateAsset, a
+// JSON object of strings. It reports false when the argument is empty, meaning
+// no metadata was given.
+func parseMetadata(metadataJSON string) (map[string]string, bool, error) {
+	if strings.TrimSpace(metadataJSON) == "" {
+		return nil, false, nil
+	}
+
+	var metadata map[string]string
+	if err := json.Unmarshal([]byte(metadataJSON), &metadata); err != nil {
+		return nil, false, fmt.Errorf("the metadata must be a JSON object of strings: %v", err)
+	}
+	if len(metadata) == 0 {
+		metadata = nil
+	}
+	return metadata, true, nil
+}
+
+// validateMetadata checks the metadata against the entry limits
+func validateMetadata(metadata map[string]string) error {
+	if len(metadata) > maxMetadataEntries {
+		return fmt.Errorf("Metadata may have at most %d entries, got %d", maxMetadataEntries, len(metadata))
+	}
+
+	keys := make([]string, 0, len(metadata))
+	for key := range metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if err := validateMetadataEntry(key, metadata[key]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateMetadataEntry checks a single metadata key and value
+func validateMetadataEntry(key string, value string) error {
+	if strings.TrimSpace(key) == "" {
+		return fmt.Errorf("Metadata keys must not be empty")
+	}
+	if len(key) > maxMetadataKeyLength {
+		return fmt.Errorf("Metadata key %q is longer than %d bytes", key, maxMetadataKeyLength)
+	}
+	if len(value) > maxMetadataValueLength {
+		return fmt.Errorf("Metadata value of %q is longer than %d bytes", key, maxMetadataValueLength)
+	}
+	return nil
+}
+
+// GetAssetMetadata returns the value of one of the asset's metadata keys
+func (s *SmartContract) GetAssetMetadata(ctx contractapi.TransactionContextInterface, dealerID string, key string) (string, error) {
+	asset, err := s.ReadAsset(ctx, dealerID)
+	if err != nil {
+		return "", err
+	}
+
+	value, ok := asset.Metadata[key]
+	if !ok {
+		return "", fmt.Errorf("the metadata key %q of asset %s does not exist", key, asset.DEALERID)
+	}
+	return value, nil
+}
+
+// SetAssetMetadata sets one of the asset's metadata keys, leaving the others and
+// the rest of the asset as they are
+func (s *SmartContract) SetAssetMetadata(ctx contractapi.TransactionContextInterface, dealerID string, key string, value string) error {
+	asset, err := s.ReadAsset(ctx, dealerID)
+	if err != nil {
+		return err
+	}
+	if err := validateMetadataEntry(key, value); err != nil {
+		return err
+	}
+
+	if asset.Metadata == nil {
+		asset.Metadata = make(map[string]string)
+	}
+	asset.Metadata[key] = value
+	if err := validateMetadata(asset.Metadata); err != nil {
+		return fmt.Errorf("invalid asset %s: %v", asset.DEALERID, err)
+	}
+
+	return putAsset(ctx, asset)
+}
+
+// DeleteAssetMetadata removes one of the asset's metadata keys
+func (s *SmartContract) DeleteAssetMetadata(ctx contractapi.TransactionContextInterface, dealerID string, key string) error {
+	asset, err := s.ReadAsset(ctx, dealerID)
+	if err != nil {
+		return err
+	}
+	if _, ok := asset.Metadata[key]; !ok {
+		return fmt.Errorf("the metadata key %q of asset %s does not exist", key, asset.DEALERID)
+	}
+
+	delete(asset.Metadata, key)
+	if len(asset.Metadata) == 0 {
+		asset.Metadata = nil
+	}
+	return putAsset(ctx, asset)
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// currentSchemaVersion is the asset layout this chaincode writes. putAsset stamps
+// it on every asset it stores.
+//
+//	1: records written before SchemaVersion existed. They may hold amounts as JSON
+//	   numbers, MSISDN and MPIN in the public state, and no CreatedAt.
+//	2: amounts as decimal strings, MSISDN and MPIN in privateCollection, CreatedAt set.
+const currentSchemaVersion = 2
+
+// MigrationResult reports what MigrateAssets did
+type MigrationResult struct {
+	TargetVersion int      `json:"targetVersion"`
+	Migrated      int      `json:"migrated"`
+	Skipped       int      `json:"skipped"` // Assets already at the target version
+	DealerIDs     []string `json:"dealerIds"`
+}
+
+// MigrateAssets lets an admin bring every asset stored by an older chaincode up to
+// targetVersion, which must be the current schema version. Older assets get the
+// missing fields filled in and are written back, which also moves legacy amounts
+// and private details to their current form. Assets already at the target are skipped.
+func (s *SmartContract) MigrateAssets(ctx contractapi.TransactionContextInterface, targetVersion int) (*MigrationResult, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	if targetVersion != currentSchemaVersion {
+		return nil, fmt.Errorf("this chaincode can only migrate assets to schema version %d, got %d", currentSchemaVersion, targetVersion)
+	}
+
+	config, err := getConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	assets, err := scanAssets(ctx, func(*Asset) bool { return true })
+	if err != nil {
+		return nil, err
+	}
+
+	result := &MigrationResult{TargetVersion: targetVersion, DealerIDs: []string{}}
+	for _, asset := range assets {
+		if assetSchemaVersion(asset) >= targetVersion {
+			result.Skipped++
+			continue
+		}
+		if err := migrateAsset(ctx, asset, config); err != nil {
+			return nil, fmt.Errorf("failed to migrate asset %s: %v", asset.DEALERID, err)
+		}
+		if err := putAsset(ctx, asset); err != nil {
+			return nil, fmt.Errorf("failed to migrate asset %s: %v", asset.DEALERID, err)
+		}
+		result.Migrated++
+		result.DealerIDs = append(result.DealerIDs, asset.DEALERID)
+	}
+	if len(result.DealerIDs) > 0 {
+		if err := setAssetEvent(ctx, assetActionUpdate, result.DealerIDs...); err != nil {
+			return nil, err
+		}
+	}
+
+	logf(levelInfo, "MigrateAssets: migrated %d assets to version %d, skipped %d", result.Migrated, targetVersion, result.Skipped)
+	return result, nil
+}
+
+// assetSchemaVersion returns the schema version an asset was stored with
+func assetSchemaVersion(asset *Asset) int {
+	if asset.SchemaVersion == 0 {
+		return 1
+	}
+	return asset.SchemaVersion
+}
+
+// migrateAsset fills in the fields a version 1 asset may lack. Amounts and
+// private details are converted by putAsset when the asset is written back.
+func migrateAsset(ctx contractapi.TransactionContextInterface, asset *Asset, config *ContractConfig) error {
+	if strings.TrimSpace(asset.STATUS) == "" {
+		asset.STATUS = config.DefaultStatus
+	}
+	if asset.CreatedAt == "" {
+		createdAt, err := assetCreatedAt(ctx, asset.DEALERID, config.MaxHistoryRecords)
+		if err != nil {
+			return err
+		}
+		if !createdAt.IsZero() {
+			asset.CreatedAt = createdAt.UTC().Format(createdAtLayout)
+		}
+	}
+	return nil
+}
+
+// assetCreatedAt finds when the asset was created from its history: the earliest
+// write since it was last deleted. It returns the zero time when the history is
+// longer than maxRecords, as the creating record may be one of those left out.
+func assetCreatedAt(ctx contractapi.TransactionContextInterface, dealerID string, maxRecords int) (time.Time, error) {
+	records, truncated, err := readAssetHistory(ctx, dealerID, maxRecords)
+	if err != nil || truncated {
+		return time.Time{}, err
+	}
+
+	var lastDelete, createdAt time.Time
+	for _, record := range records {
+		if record.IsDelete && record.Timestamp.After(lastDelete) {
+			lastDelete = record.Timestamp
+		}
+	}
+	for _, record := range records {
+		if record.IsDelete || !record.Timestamp.After(lastDelete) {
+			continue
+		}
+		if createdAt.IsZero() || record.Timestamp.Before(createdAt) {
+			createdAt = record.Timestamp
+		}
+	}
+	return createdAt, nil
+}
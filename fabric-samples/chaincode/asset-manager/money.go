@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Money is an amount with exactly two decimal places, such as "100.50". It is
+// stored and returned as a decimal string, and all arithmetic is done in
+// integer cents so that sums like 0.10 + 0.20 are exact.
+//
+// Records written before Money existed hold BALANCE and TRANSAMOUNT as JSON
+// numbers. UnmarshalJSON still accepts those, rounding them to the nearest cent,
+// and they are rewritten as strings the next time the asset is saved.
+type Money string
+
+// maxMoneyDigits caps the whole part of an amount so that adding two amounts
+// in cents can never overflow an int64
+const maxMoneyDigits = 15
+
+// parseMoney converts a decimal string such as "12", "12.5" or "-0.25" to cents.
+// More than two decimal places, exponents and other non-decimal forms are rejected.
+func parseMoney(value string) (int64, error) {
+	s := strings.TrimSpace(value)
+	negative := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+
+	whole, fraction, hasPoint := strings.Cut(s, ".")
+	if whole == "" || (hasPoint && fraction == "") || !isDigits(whole) || !isDigits(fraction) {
+		return 0, fmt.Errorf("%q is not a decimal amount", value)
+	}
+	if len(fraction) > 2 {
+		return 0, fmt.Errorf("%q has more than two decimal places", value)
+	}
+	whole = strings.TrimLeft(whole, "0")
+	if len(whole) > maxMoneyDigits {
+		return 0, fmt.Errorf("%q is too large", value)
+	}
+
+	cents, _ := strconv.ParseInt(whole+(fraction + "00")[:2], 10, 64)
+	if negative {
+		cents = -cents
+	}
+	return cents, nil
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// moneyFromCents formats an amount in cents as Money
+func moneyFromCents(cents int64) Money {
+	sign := ""
+	if cents < 0 {
+		sign = "-"
+		cents = -cents
+	}
+	return Money(fmt.Sprintf("%s%d.%02d", sign, cents/100, cents%100))
+}
+
+// newMoney parses a decimal string passed to a transaction into Money
+func newMoney(field string, value string) (Money, error) {
+	cents, err := parseMoney(value)
+	if err != nil {
+		return "", fmt.Errorf("invalid %s: %v", field, err)
+	}
+	return moneyFromCents(cents), nil
+}
+
+// cents returns the amount in cents. The empty Money is zero. Money only holds
+// values produced by parseMoney, so a parse failure can't happen in practice.
+func (m Money) cents() int64 {
+	if m == "" {
+		return 0
+	}
+	cents, _ := parseMoney(string(m))
+	return cents
+}
+
+// UnmarshalJSON accepts a decimal string, or a JSON number from a record
+// written before amounts were stored as strings
+func (m *Money) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err == nil {
+		cents, err := parseMoney(value)
+		if err != nil {
+			return err
+		}
+		*m = moneyFromCents(cents)
+		return nil
+	}
+
+	var legacy float64
+	if err := json.Unmarshal(data, &legacy); err != nil {
+		return fmt.Errorf("an amount must be a decimal string, got %s", data)
+	}
+	if math.Abs(legacy) >= math.Pow10(maxMoneyDigits) {
+		return fmt.Errorf("the amount %v is too large", legacy)
+	}
+	*m = moneyFromCents(int64(math.Round(legacy * 100)))
+	return nil
+}
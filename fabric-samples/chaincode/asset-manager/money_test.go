@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		value string
+		cents int64
+	}{
+		{"0", 0},
+		{"12", 1200},
+		{"12.5", 1250},
+		{"12.05", 1205},
+		{"-0.25", -25},
+		{" 7.10 ", 710},
+		{"007.10", 710},
+		{"0.10", 10},
+		{"999999999999999.99", 99999999999999999},
+	}
+	for _, test := range tests {
+		cents, err := parseMoney(test.value)
+		if err != nil {
+			t.Errorf("parseMoney(%q) returned error: %v", test.value, err)
+			continue
+		}
+		if cents != test.cents {
+			t.Errorf("parseMoney(%q) = %d, want %d", test.value, cents, test.cents)
+		}
+	}
+}
+
+func TestParseMoneyRejectsInvalidAmounts(t *testing.T) {
+	tests := []string{
+		"",
+		"-",
+		".5",
+		"5.",
+		"1.234",
+		"1e3",
+		"+1",
+		"1,000",
+		"12.5.0",
+		"abc",
+		"0x10",
+		"NaN",
+		"1000000000000000",
+		"-1000000000000000.00",
+	}
+	for _, value := range tests {
+		if cents, err := parseMoney(value); err == nil {
+			t.Errorf("parseMoney(%q) = %d, want an error", value, cents)
+		}
+	}
+}
+
+func TestMoneyFromCents(t *testing.T) {
+	tests := []struct {
+		cents int64
+		money Money
+	}{
+		{0, "0.00"},
+		{5, "0.05"},
+		{-5, "-0.05"},
+		{100, "1.00"},
+		{-100, "-1.00"},
+		{123456, "1234.56"},
+		{99999999999999999, "999999999999999.99"},
+	}
+	for _, test := range tests {
+		if money := moneyFromCents(test.cents); money != test.money {
+			t.Errorf("moneyFromCents(%d) = %q, want %q", test.cents, money, test.money)
+		}
+	}
+}
+
+func TestMoneyArithmeticIsExact(t *testing.T) {
+	a, err := parseMoney("0.10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := parseMoney("0.20")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum := moneyFromCents(a + b); sum != "0.30" {
+		t.Errorf("0.10 + 0.20 = %s, want 0.30", sum)
+	}
+}
+
+func TestMoneyUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		json  string
+		money Money
+	}{
+		{`"100.5"`, "100.50"},
+		{`"-3"`, "-3.00"},
+		// Records written before amounts were strings hold JSON numbers
+		{`100.5`, "100.50"},
+		{`1e6`, "1000000.00"},
+		{`0.125`, "0.13"},
+	}
+	for _, test := range tests {
+		var money Money
+		if err := json.Unmarshal([]byte(test.json), &money); err != nil {
+			t.Errorf("unmarshal %s returned error: %v", test.json, err)
+			continue
+		}
+		if money != test.money {
+			t.Errorf("unmarshal %s = %q, want %q", test.json, money, test.money)
+		}
+	}
+
+	for _, invalid := range []string{`"1.234"`, `"abc"`, `true`, `1e15`} {
+		var money Money
+		if err := json.Unmarshal([]byte(invalid), &money); err == nil {
+			t.Errorf("unmarshal %s = %q, want an error", invalid, money)
+		}
+	}
+}
+
+func TestAssetAmountsMarshalAsTwoDecimalStrings(t *testing.T) {
+	// A record written while BALANCE and TRANSAMOUNT were float64
+	legacy := `{"DEALERID":"D1","BALANCE":1000000,"TRANSAMOUNT":1e+06}`
+
+	var asset Asset
+	if err := json.Unmarshal([]byte(legacy), &asset); err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(&asset)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatal(err)
+	}
+	for _, field := range []string{"BALANCE", "TRANSAMOUNT"} {
+		if value, ok := fields[field].(string); !ok || value != "1000000.00" {
+			t.Errorf("%s marshals as %v, want \"1000000.00\" in %s", field, fields[field], data)
+		}
+	}
+}
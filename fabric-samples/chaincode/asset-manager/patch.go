@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// patchProtectedFields can't be changed through a merge patch, either because they
+// identify the asset or because only the chaincode itself maintains them
+var patchProtectedFields = []string{"DEALERID", "LastTransferTxID", "CreatedAt", "LastModifiedBy", "SchemaVersion"}
+
+// UpdateAssetFields applies a JSON merge patch (RFC 7386) to an existing asset.
+// The merge happens inside the transaction, so concurrent updates can't be lost.
+// A new MPIN comes from the transient map, never from the patch.
+func (s *SmartContract) UpdateAssetFields(ctx contractapi.TransactionContextInterface, dealerID string, patchJSON string) error {
+	dealerID = normalizeDealerID(dealerID)
+	if err := validateDealerID(dealerID); err != nil {
+		return err
+	}
+
+	var patch map[string]any
+	if err := json.Unmarshal([]byte(patchJSON), &patch); err != nil || patch == nil {
+		return fmt.Errorf("the patch must be a JSON object")
+	}
+	for _, field := range patchProtectedFields {
+		if _, ok := patch[field]; ok {
+			return fmt.Errorf("the field %s cannot be changed", field)
+		}
+	}
+	if _, ok := patch["MPIN"]; ok {
+		return fmt.Errorf("the MPIN cannot be patched, pass it in the transient map under %q", mpinTransientKey)
+	}
+
+	existing, err := s.ReadAsset(ctx, dealerID)
+	if err != nil {
+		return err
+	}
+
+	existingJSON, err := json.Marshal(existing)
+	if err != nil {
+		return err
+	}
+	var current map[string]any
+	if err := json.Unmarshal(existingJSON, &current); err != nil {
+		return err
+	}
+
+	mergedJSON, err := json.Marshal(mergePatch(current, patch))
+	if err != nil {
+		return err
+	}
+
+	// Reject unknown fields and wrongly typed values instead of silently dropping them
+	var asset Asset
+	decoder := json.NewDecoder(bytes.NewReader(mergedJSON))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&asset); err != nil {
+		return fmt.Errorf("the patch does not produce a valid asset: %v", err)
+	}
+	if mpin, ok, err := transientMPIN(ctx); err != nil {
+		return err
+	} else if ok {
+		if asset.MPIN, err = hashMPIN(ctx, mpin); err != nil {
+			return err
+		}
+	}
+
+	if err := validateAsset(&asset); err != nil {
+		return err
+	}
+	if err := checkConfiguredLimits(ctx, &asset); err != nil {
+		return err
+	}
+	if err := checkAssetUpdate(ctx, existing, &asset); err != nil {
+		return err
+	}
+
+	return putAsset(ctx, &asset)
+}
+
+// mergePatch applies patch to target following RFC 7386: null removes a member,
+// objects are merged recursively and any other value replaces the target's
+func mergePatch(target any, patch any) any {
+	patchObject, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]any)
+	if !ok {
+		targetObject = make(map[string]any)
+	}
+	for name, value := range patchObject {
+		if value == nil {
+			delete(targetObject, name)
+		} else {
+			targetObject[name] = mergePatch(targetObject[name], value)
+		}
+	}
+	return targetObject
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"golang.org/x/crypto/scrypt"
+)
+
+// mpinTransientKey is the transient map entry that carries the MPIN, so the PIN
+// never appears in the transaction arguments, the endorsement logs or the ledger
+const mpinTransientKey = "MPIN"
+
+// mpinScryptPrefix marks an MPIN stored as "scrypt:<hex salt>:<hex key>", derived
+// with the mpinScrypt parameters. Changing them needs a new prefix so older
+// hashes still verify.
+const mpinScryptPrefix = "scrypt:"
+
+// mpinScrypt are the scrypt cost parameters for MPIN hashes: 32 MiB and roughly
+// 100ms per hash, so guessing a short PIN from a leaked hash is slow
+const (
+	mpinScryptN      = 1 << 15
+	mpinScryptR      = 8
+	mpinScryptP      = 1
+	mpinScryptKeyLen = 32
+)
+
+// mpinHashPrefix marks an MPIN stored by an older version as
+// "sha256:<transaction ID>:<hex digest>". Assets written before MPINs were
+// hashed still hold the raw PIN. Both are verified but no longer written.
+const mpinHashPrefix = "sha256:"
+
+// VerifyMPIN reports whether the MPIN in the transient map matches the asset's
+// stored MPIN without returning the stored value. Evaluate it rather than
+// submitting it; nothing is written either way. The MPIN is private data, so
+// only Org1 peers can answer.
+func (s *SmartContract) VerifyMPIN(ctx contractapi.TransactionContextInterface, dealerID string) (bool, error) {
+	dealerID = normalizeDealerID(dealerID)
+	mpin, ok, err := transientMPIN(ctx)
+	if err != nil {
+		return false, err
+	}
+	if !ok {
+		return false, fmt.Errorf("the MPIN must be passed in the transient map under %q", mpinTransientKey)
+	}
+
+	asset, err := s.ReadAsset(ctx, dealerID)
+	if err != nil {
+		return false, err
+	}
+	stored, err := readPrivateMPIN(ctx, asset)
+	if err != nil {
+		return false, err
+	}
+	return mpinMatches(stored, mpin), nil
+}
+
+// transientMPIN returns the MPIN from the transient map, and whether one was sent
+func transientMPIN(ctx contractapi.TransactionContextInterface) (string, bool, error) {
+	transient, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return "", false, fmt.Errorf("failed to read the transient map: %v", err)
+	}
+	mpin, ok := transient[mpinTransientKey]
+	if !ok {
+		return "", false, nil
+	}
+	return string(mpin), true, nil
+}
+
+// hashMPIN returns the stored form of mpin, an scrypt key salted with the random
+// salt from the transient map. The salt is stored with the key in the private
+// collection only, so nobody outside it can test guesses against the ledger.
+func hashMPIN(ctx contractapi.TransactionContextInterface, mpin string) (string, error) {
+	if mpin == "" {
+		return "", nil
+	}
+	salt, err := transientSalt(ctx)
+	if err != nil {
+		return "", err
+	}
+	key, err := mpinScryptKey(salt, mpin)
+	if err != nil {
+		return "", err
+	}
+	return mpinScryptPrefix + hex.EncodeToString(salt) + ":" + hex.EncodeToString(key), nil
+}
+
+func mpinScryptKey(salt []byte, mpin string) ([]byte, error) {
+	key, err := scrypt.Key([]byte(mpin), salt, mpinScryptN, mpinScryptR, mpinScryptP, mpinScryptKeyLen)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash the MPIN: %v", err)
+	}
+	return key, nil
+}
+
+func mpinDigest(salt string, mpin string) string {
+	digest := sha256.Sum256([]byte(salt + ":" + mpin))
+	return hex.EncodeToString(digest[:])
+}
+
+// mpinMatches compares mpin with a stored MPIN in constant time, so response
+// timing doesn't leak the PIN
+func mpinMatches(stored string, mpin string) bool {
+	if hashed, ok := strings.CutPrefix(stored, mpinScryptPrefix); ok {
+		saltHex, keyHex, ok := strings.Cut(hashed, ":")
+		if !ok {
+			return false
+		}
+		salt, err := hex.DecodeString(saltHex)
+		if err != nil {
+			return false
+		}
+		key, err := mpinScryptKey(salt, mpin)
+		if err != nil {
+			return false
+		}
+		return subtle.ConstantTimeCompare([]byte(keyHex), []byte(hex.EncodeToString(key))) == 1
+	}
+
+	hashed, ok := strings.CutPrefix(stored, mpinHashPrefix)
+	if !ok {
+		return subtle.ConstantTimeCompare([]byte(stored), []byte(mpin)) == 1
+	}
+	salt, digest, ok := strings.Cut(hashed, ":")
+	if !ok {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(digest), []byte(mpinDigest(salt, mpin))) == 1
+}
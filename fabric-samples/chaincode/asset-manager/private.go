@@ -0,0 +1,175 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// privateCollection holds the sensitive asset fields, MSISDN and the MPIN hash.
+// It is defined in collections_config.json, which must be passed when the
+// chaincode is approved and committed, and only Org1 peers store or read it.
+const privateCollection = "dealerPrivateDetails"
+
+// Private data keys are composite keys (msisdn~dealerID and mpin~dealerID), one per
+// field, so each can be written blindly. Org2 peers can then endorse writes to a
+// field without being able to read the other one.
+const (
+	privateMSISDNType = "msisdn"
+	privateMPINType   = "mpin"
+)
+
+// saltTransientKey is the transient map entry that carries a random salt from the
+// client. Chaincode can't generate randomness, as every endorser must compute the
+// same value, so the client sends at least minSaltBytes of it with every write of
+// a private field.
+const (
+	saltTransientKey = "SALT"
+	minSaltBytes     = 16
+)
+
+// privateValue is how a private field is stored. The ledger keeps a hash of every
+// private value, and the random salt stops anyone from confirming a guessed MSISDN
+// or MPIN against it.
+type privateValue struct {
+	Salt  string `json:"salt"`
+	Value string `json:"value"`
+}
+
+// AssetPrivateDetails are the fields of an asset kept out of the public state
+type AssetPrivateDetails struct {
+	DEALERID string `json:"DEALERID"`
+	MSISDN   string `json:"MSISDN"`
+	MPIN     string `json:"MPIN"` // Salted hash of the PIN, see hashMPIN
+}
+
+// ReadAssetPrivate returns the private details of an asset. The collection only
+// lets members read it, so this fails for clients and peers outside Org1.
+func (s *SmartContract) ReadAssetPrivate(ctx contractapi.TransactionContextInterface, dealerID string) (*AssetPrivateDetails, error) {
+	dealerID = normalizeDealerID(dealerID)
+	exists, err := s.AssetExists(ctx, dealerID)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, fmt.Errorf("the asset %s does not exist", dealerID)
+	}
+
+	msisdn, err := getPrivateField(ctx, privateMSISDNType, dealerID)
+	if err != nil {
+		return nil, err
+	}
+	mpin, err := getPrivateField(ctx, privateMPINType, dealerID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AssetPrivateDetails{
+		DEALERID: dealerID,
+		MSISDN:   msisdn,
+		MPIN:     mpin,
+	}, nil
+}
+
+// readPrivateMPIN returns the stored MPIN of an asset, preferring the private
+// collection over a value left in the public state by an older version
+func readPrivateMPIN(ctx contractapi.TransactionContextInterface, asset *Asset) (string, error) {
+	mpin, err := getPrivateField(ctx, privateMPINType, asset.DEALERID)
+	if err != nil {
+		return "", err
+	}
+	if mpin == "" {
+		return asset.MPIN, nil
+	}
+	return mpin, nil
+}
+
+// movePrivateFields writes the asset's MSISDN and MPIN to the private collection
+// and blanks them so they never reach the public state. Empty fields are left
+// untouched in the collection, so writes that don't know the MPIN keep it. Each
+// value is stored with a salt derived from the transient map, see privateValue.
+func movePrivateFields(ctx contractapi.TransactionContextInterface, asset *Asset) error {
+	if asset.MSISDN != "" {
+		if err := putPrivateField(ctx, privateMSISDNType, asset.DEALERID, asset.MSISDN); err != nil {
+			return err
+		}
+	}
+	if asset.MPIN != "" {
+		if err := putPrivateField(ctx, privateMPINType, asset.DEALERID, asset.MPIN); err != nil {
+			return err
+		}
+	}
+	asset.MSISDN = ""
+	asset.MPIN = ""
+	return nil
+}
+
+// deletePrivateFields removes an asset's private details
+func deletePrivateFields(ctx contractapi.TransactionContextInterface, dealerID string) error {
+	for _, objectType := range []string{privateMSISDNType, privateMPINType} {
+		key, err := ctx.GetStub().CreateCompositeKey(objectType, []string{dealerID})
+		if err != nil {
+			return fmt.Errorf("failed to create private %s key: %v", objectType, err)
+		}
+		if err := ctx.GetStub().DelPrivateData(privateCollection, key); err != nil {
+			return fmt.Errorf("failed to delete private %s of asset %s: %v", objectType, dealerID, err)
+		}
+	}
+	return nil
+}
+
+// transientSalt returns the random salt from the transient map
+func transientSalt(ctx contractapi.TransactionContextInterface) ([]byte, error) {
+	transient, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the transient map: %v", err)
+	}
+	salt := transient[saltTransientKey]
+	if len(salt) < minSaltBytes {
+		return nil, fmt.Errorf("a random salt of at least %d bytes must be passed in the transient map under %q", minSaltBytes, saltTransientKey)
+	}
+	return salt, nil
+}
+
+func getPrivateField(ctx contractapi.TransactionContextInterface, objectType string, dealerID string) (string, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(objectType, []string{dealerID})
+	if err != nil {
+		return "", fmt.Errorf("failed to create private %s key: %v", objectType, err)
+	}
+	value, err := ctx.GetStub().GetPrivateData(privateCollection, key)
+	if err != nil {
+		return "", fmt.Errorf("failed to read private %s of asset %s: %v", objectType, dealerID, err)
+	}
+
+	// Values written before they were salted are stored as they are
+	var stored privateValue
+	if err := json.Unmarshal(value, &stored); err != nil || stored.Salt == "" {
+		return string(value), nil
+	}
+	return stored.Value, nil
+}
+
+func putPrivateField(ctx contractapi.TransactionContextInterface, objectType string, dealerID string, value string) error {
+	key, err := ctx.GetStub().CreateCompositeKey(objectType, []string{dealerID})
+	if err != nil {
+		return fmt.Errorf("failed to create private %s key: %v", objectType, err)
+	}
+	salt, err := transientSalt(ctx)
+	if err != nil {
+		return err
+	}
+	// The salt is hashed with the field type so the two fields of an asset
+	// don't share one
+	saltDigest := sha256.Sum256(append([]byte(objectType+":"), salt...))
+	storedJSON, err := json.Marshal(privateValue{Salt: hex.EncodeToString(saltDigest[:]), Value: value})
+	if err != nil {
+		return err
+	}
+	if err := ctx.GetStub().PutPrivateData(privateCollection, key, storedJSON); err != nil {
+		return fmt.Errorf("failed to write private %s of asset %s: %v", objectType, dealerID, err)
+	}
+	return nil
+}
@@ -0,0 +1,345 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// GetTotalBalance returns the sum of BALANCE across all assets
+func (s *SmartContract) GetTotalBalance(ctx contractapi.TransactionContextInterface) (Money, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByRange("", "")
+	if err != nil {
+		return "", fmt.Errorf("failed to get state by range: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	var total int64
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return "", fmt.Errorf("failed to get next state from iterator: %v", err)
+		}
+
+		var asset Asset
+		if err := json.Unmarshal(queryResponse.Value, &asset); err != nil {
+			return "", fmt.Errorf("failed to unmarshal asset JSON: %v", err)
+		}
+		total += asset.BALANCE.cents()
+	}
+
+	return moneyFromCents(total), nil
+}
+
+// AssetStats holds balance aggregates across all assets
+type AssetStats struct {
+	Count          int   `json:"count"`
+	TotalBalance   Money `json:"totalBalance"`
+	AverageBalance Money `json:"averageBalance"`
+	MinBalance     Money `json:"minBalance"`
+	MaxBalance     Money `json:"maxBalance"`
+}
+
+// GetAssetStats returns the count, total, average, minimum and maximum BALANCE
+// of all assets in a single pass. An empty ledger gives all zeros. The average
+// is rounded down to the cent.
+func (s *SmartContract) GetAssetStats(ctx contractapi.TransactionContextInterface) (*AssetStats, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByRange("", "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get state by range: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	var count int
+	var total, lowest, highest int64
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get next state from iterator: %v", err)
+		}
+
+		var asset Asset
+		if err := json.Unmarshal(queryResponse.Value, &asset); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal asset JSON: %v", err)
+		}
+
+		balance := asset.BALANCE.cents()
+		if count == 0 || balance < lowest {
+			lowest = balance
+		}
+		if count == 0 || balance > highest {
+			highest = balance
+		}
+		count++
+		total += balance
+	}
+
+	var average int64
+	if count > 0 {
+		average = total / int64(count)
+	}
+	return &AssetStats{
+		Count:          count,
+		TotalBalance:   moneyFromCents(total),
+		AverageBalance: moneyFromCents(average),
+		MinBalance:     moneyFromCents(lowest),
+		MaxBalance:     moneyFromCents(highest),
+	}, nil
+}
+
+// GetAssetsCreatedBetween returns the assets whose CreatedAt lies between
+// start and end (inclusive), both given as RFC3339 timestamps.
+// This is a rich query; on LevelDB it falls back to scanning every asset.
+func (s *SmartContract) GetAssetsCreatedBetween(ctx contractapi.TransactionContextInterface, startRFC3339 string, endRFC3339 string) ([]*Asset, error) {
+	start, err := time.Parse(time.RFC3339, startRFC3339)
+	if err != nil {
+		return nil, fmt.Errorf("invalid start time %q, expected RFC3339: %v", startRFC3339, err)
+	}
+	end, err := time.Parse(time.RFC3339, endRFC3339)
+	if err != nil {
+		return nil, fmt.Errorf("invalid end time %q, expected RFC3339: %v", endRFC3339, err)
+	}
+	if end.Before(start) {
+		return nil, fmt.Errorf("end time %s is before start time %s", endRFC3339, startRFC3339)
+	}
+
+	from, to := start.UTC().Format(createdAtLayout), end.UTC().Format(createdAtLayout)
+	query := map[string]interface{}{
+		"selector": map[string]interface{}{
+			"CreatedAt": map[string]string{
+				"$gte": from,
+				"$lte": to,
+			},
+		},
+		"use_index": []string{"_design/indexCreatedAtDoc", "indexCreatedAt"},
+	}
+	queryJSON, err := json.Marshal(query)
+	if err != nil {
+		return nil, err
+	}
+
+	return getQueryResultForQueryString(ctx, string(queryJSON), func(asset *Asset) bool {
+		return asset.CreatedAt >= from && asset.CreatedAt <= to
+	})
+}
+
+// getQueryResultForQueryString executes a CouchDB rich query and returns the matching assets.
+// LevelDB doesn't support rich queries, so there it scans every asset instead and
+// keeps those for which match, which must select the same assets as the query, is true.
+func getQueryResultForQueryString(ctx contractapi.TransactionContextInterface, queryString string, match func(*Asset) bool) ([]*Asset, error) {
+	resultsIterator, err := ctx.GetStub().GetQueryResult(queryString)
+	if isRichQueryUnsupported(err) {
+		logf(levelDebug, "Rich queries are not supported by the state database, scanning all assets")
+		return scanAssets(ctx, match)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to run rich query: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	var assets []*Asset
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get next query result from iterator: %v", err)
+		}
+
+		var asset Asset
+		if err := json.Unmarshal(queryResponse.Value, &asset); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal asset JSON: %v", err)
+		}
+		// Archived copies live under composite keys but hold the same document,
+		// so only results stored under their own DEALERID are assets
+		if queryResponse.Key != asset.DEALERID {
+			continue
+		}
+		assets = append(assets, &asset)
+	}
+
+	return assets, nil
+}
+
+// isRichQueryUnsupported reports whether a rich query failed because the peer
+// uses LevelDB as its state database
+func isRichQueryUnsupported(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "not supported for leveldb")
+}
+
+// scanAssets returns the assets for which match is true by reading every asset,
+// the fallback for rich queries on LevelDB
+func scanAssets(ctx contractapi.TransactionContextInterface, match func(*Asset) bool) ([]*Asset, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByRange("", "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get state by range: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	var assets []*Asset
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get next state from iterator: %v", err)
+		}
+
+		var asset Asset
+		if err := json.Unmarshal(queryResponse.Value, &asset); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal asset JSON: %v", err)
+		}
+		if match(&asset) {
+			assets = append(assets, &asset)
+		}
+	}
+
+	return assets, nil
+}
+
+// GetAssetsModifiedBy returns the assets last written by the given client identity,
+// as returned by GetClientIdentity().GetID() and recorded in LastModifiedBy.
+// This is a rich query; on LevelDB it falls back to scanning every asset.
+func (s *SmartContract) GetAssetsModifiedBy(ctx contractapi.TransactionContextInterface, identityID string) ([]*Asset, error) {
+	if identityID == "" {
+		return nil, fmt.Errorf("the identity ID must not be empty")
+	}
+
+	query := map[string]interface{}{
+		"selector": map[string]interface{}{
+			"LastModifiedBy": identityID,
+		},
+		"use_index": []string{"_design/indexLastModifiedByDoc", "indexLastModifiedBy"},
+	}
+	queryJSON, err := json.Marshal(query)
+	if err != nil {
+		return nil, err
+	}
+
+	return getQueryResultForQueryString(ctx, string(queryJSON), func(asset *Asset) bool {
+		return asset.LastModifiedBy == identityID
+	})
+}
+
+// GetAssetsByPrefix returns the assets whose DEALERID starts with prefix, such as
+// every dealer under "REGION1-". It is a key range scan, so it works on LevelDB too.
+func (s *SmartContract) GetAssetsByPrefix(ctx contractapi.TransactionContextInterface, prefix string) ([]*Asset, error) {
+	prefix = normalizeDealerID(prefix)
+	if prefix == "" {
+		return nil, fmt.Errorf("the prefix must not be empty, use GetAllAssets to list every asset")
+	}
+
+	// Every key starting with prefix sorts below prefix followed by the highest rune
+	resultsIterator, err := ctx.GetStub().GetStateByRange(prefix, prefix+string(utf8.MaxRune))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get state by range: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	var assets []*Asset
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get next state from iterator: %v", err)
+		}
+
+		var asset Asset
+		if err := json.Unmarshal(queryResponse.Value, &asset); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal asset JSON: %v", err)
+		}
+		assets = append(assets, &asset)
+	}
+
+	return assets, nil
+}
+
+// AssetPage is one page of assets in key order
+type AssetPage struct {
+	Assets   []*Asset `json:"assets"`
+	Bookmark string   `json:"bookmark"` // Pass to the next call to continue after this page
+	HasMore  bool     `json:"hasMore"`
+}
+
+// GetAssetsPage returns at most pageSize assets starting at bookmark, which is
+// empty for the first page, so the whole ledger can be listed without any single
+// response holding it all. Pagination is only allowed in evaluated transactions.
+func (s *SmartContract) GetAssetsPage(ctx contractapi.TransactionContextInterface, pageSize int, bookmark string) (*AssetPage, error) {
+	if pageSize <= 0 {
+		return nil, fmt.Errorf("page size must be positive, got %d", pageSize)
+	}
+
+	resultsIterator, metadata, err := ctx.GetStub().GetStateByRangeWithPagination("", "", int32(pageSize), bookmark)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get state by range: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	page := &AssetPage{
+		Assets: []*Asset{},
+	}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get next state from iterator: %v", err)
+		}
+
+		var asset Asset
+		if err := json.Unmarshal(queryResponse.Value, &asset); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal asset JSON: %v", err)
+		}
+		page.Assets = append(page.Assets, &asset)
+	}
+
+	// A full page may be followed by more, a short one is the last
+	page.Bookmark = metadata.GetBookmark()
+	page.HasMore = len(page.Assets) == pageSize && page.Bookmark != ""
+	return page, nil
+}
+
+// maxBatchReadIDs bounds how many assets one ReadAssets call can fetch
+const maxBatchReadIDs = 100
+
+// AssetReadResult is the outcome of reading one dealer ID in ReadAssets:
+// the asset, or the reason it couldn't be read
+type AssetReadResult struct {
+	Asset *Asset `json:"asset,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// ReadAssets reads up to 100 assets in one call. idsJSON is a JSON array of dealer
+// IDs and the result maps each normalized ID to its asset, or to an error when it
+// doesn't exist, so one missing asset doesn't fail the whole batch.
+func (s *SmartContract) ReadAssets(ctx contractapi.TransactionContextInterface, idsJSON string) (map[string]*AssetReadResult, error) {
+	var ids []string
+	if err := json.Unmarshal([]byte(idsJSON), &ids); err != nil {
+		return nil, fmt.Errorf("the dealer IDs must be a JSON array of strings: %v", err)
+	}
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("at least one dealer ID is required")
+	}
+	if len(ids) > maxBatchReadIDs {
+		return nil, fmt.Errorf("at most %d dealer IDs can be read at once, got %d", maxBatchReadIDs, len(ids))
+	}
+
+	results := make(map[string]*AssetReadResult, len(ids))
+	for _, id := range ids {
+		dealerID := normalizeDealerID(id)
+		if dealerID == "" {
+			return nil, fmt.Errorf("the dealer IDs must not be empty")
+		}
+		if _, seen := results[dealerID]; seen {
+			continue
+		}
+
+		asset, err := readStoredAsset(ctx, dealerID)
+		if err != nil {
+			return nil, err
+		}
+		if asset == nil {
+			results[dealerID] = &AssetReadResult{Error: fmt.Sprintf("the asset %s does not exist", dealerID)}
+			continue
+		}
+		results[dealerID] = &AssetReadResult{Asset: asset}
+	}
+	return results, nil
+}
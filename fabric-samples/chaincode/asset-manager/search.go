@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// maxSearchLength bounds the search text so a query stays cheap to run
+const maxSearchLength = 100
+
+// searchFields are the Asset fields SearchAssets matches against. MSISDN is in
+// the private data collection, so it isn't part of the world state searched here.
+var searchFields = []string{"REMARKS", "STATUS"}
+
+// AssetSearchResult is an asset matched by SearchAssets
+type AssetSearchResult struct {
+	Asset         *Asset   `json:"asset"`
+	MatchedFields []string `json:"matchedFields"`
+}
+
+// SearchAssets returns the assets whose REMARKS or STATUS contain text, ignoring
+// case, with the fields that matched. On CouchDB it runs a $regex rich query; on
+// LevelDB, where rich queries aren't supported, it scans every asset instead.
+func (s *SmartContract) SearchAssets(ctx contractapi.TransactionContextInterface, text string) ([]*AssetSearchResult, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, fmt.Errorf("the search text must not be empty")
+	}
+	if len(text) > maxSearchLength {
+		return nil, fmt.Errorf("the search text must be at most %d characters", maxSearchLength)
+	}
+
+	assets, err := searchAssetsRich(ctx, text)
+	if err != nil {
+		return nil, err
+	}
+
+	results := []*AssetSearchResult{}
+	for _, asset := range assets {
+		if matched := matchedSearchFields(asset, text); len(matched) > 0 {
+			results = append(results, &AssetSearchResult{Asset: asset, MatchedFields: matched})
+		}
+	}
+	return results, nil
+}
+
+// searchAssetsRich finds candidate assets with a CouchDB $regex selector
+func searchAssetsRich(ctx contractapi.TransactionContextInterface, text string) ([]*Asset, error) {
+	pattern := "(?i)" + regexp.QuoteMeta(text)
+	conditions := make([]map[string]interface{}, 0, len(searchFields))
+	for _, field := range searchFields {
+		conditions = append(conditions, map[string]interface{}{
+			field: map[string]string{"$regex": pattern},
+		})
+	}
+
+	query := map[string]interface{}{
+		"selector": map[string]interface{}{
+			"$or": conditions,
+		},
+	}
+	queryJSON, err := json.Marshal(query)
+	if err != nil {
+		return nil, err
+	}
+	return getQueryResultForQueryString(ctx, string(queryJSON), func(asset *Asset) bool {
+		return len(matchedSearchFields(asset, text)) > 0
+	})
+}
+
+// matchedSearchFields returns the searchFields of the asset that contain text, ignoring case
+func matchedSearchFields(asset *Asset, text string) []string {
+	text = strings.ToLower(text)
+	values := map[string]string{
+		"REMARKS": asset.REMARKS,
+		"STATUS":  asset.STATUS,
+	}
+
+	var matched []string
+	for _, field := range searchFields {
+		if strings.Contains(strings.ToLower(values[field]), text) {
+			matched = append(matched, field)
+		}
+	}
+	return matched
+}
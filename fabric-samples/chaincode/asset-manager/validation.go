@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// statusPattern is the shape of a valid STATUS, e.g. ACTIVE or FROZEN
+var statusPattern = regexp.MustCompile(`^[A-Z][A-Z0-9_]*$`)
+
+// validateAsset checks the fields every stored asset must have. All problems are
+// reported in a single error so clients can fix them in one go.
+func validateAsset(a *Asset) error {
+	var problems []string
+	if a.DEALERID == "" {
+		problems = append(problems, "DEALERID must not be empty")
+	}
+	if err := validateStatus(a.STATUS); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if err := validateMetadata(a.Metadata); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if a.Currency != "" {
+		if err := validateCurrency(a.Currency, nil); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid asset %s: %s", a.DEALERID, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// validateMSISDN checks the MSISDN given to CreateAsset or UpdateAsset. Stored
+// assets keep it in the private collection, so validateAsset can't check it.
+func validateMSISDN(dealerID string, msisdn string) error {
+	if strings.TrimSpace(msisdn) == "" {
+		return fmt.Errorf("invalid asset %s: MSISDN must not be empty", dealerID)
+	}
+	return nil
+}
+
+// validateStatus checks that status is an uppercase word such as ACTIVE
+func validateStatus(status string) error {
+	if !statusPattern.MatchString(status) {
+		return fmt.Errorf("STATUS must be an uppercase word such as %s, got %q", statusActive, status)
+	}
+	return nil
+}